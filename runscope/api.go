@@ -0,0 +1,152 @@
+package runscope
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	runscope "github.com/ewilde/go-runscope"
+)
+
+// apiResponse mirrors the envelope every Runscope api response is wrapped in.
+type apiResponse struct {
+	Data  interface{} `json:"data"`
+	Error struct {
+		Status       int    `json:"status"`
+		ErrorMessage string `json:"error"`
+	} `json:"error"`
+}
+
+// apiRequest calls the Runscope api with the credentials of client and
+// returns the decoded "data" element of the response. It's used for the
+// endpoints and fields the go-runscope client doesn't support yet.
+func apiRequest(client *runscope.Client, method string, endpoint string, body interface{}) (interface{}, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("[DEBUG] request: %s %s %s", method, endpoint, redactSecrets(payload))
+		bodyReader = bytes.NewReader(payload)
+	} else {
+		log.Printf("[DEBUG] request: %s %s", method, endpoint)
+	}
+
+	req, err := http.NewRequest(method, client.APIURL+endpoint, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("Error during creation of request: %s", err)
+	}
+
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", client.AccessToken))
+	req.Header.Add("Accept", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	resp, err := client.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] response: %d %s", resp.StatusCode, redactSecrets(bodyBytes))
+
+	response := new(apiResponse)
+	if err := json.Unmarshal(bodyBytes, response); err != nil && resp.StatusCode < 300 {
+		return nil, fmt.Errorf("failed to Unmarshal response body: %v", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("Status: %s Error calling %s %s, reason: %q",
+			resp.Status, method, endpoint, response.Error.ErrorMessage)
+	}
+
+	return response.Data, nil
+}
+
+// decodeAPIData decodes the "data" of an api response into result. Runscope
+// sends times as unix timestamps, these are converted first so they decode
+// into the time.Time fields of the go-runscope types.
+func decodeAPIData(data interface{}, result interface{}) error {
+	encoded, err := json.Marshal(convertTimestamps("", data))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(encoded, result)
+}
+
+func convertTimestamps(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			converted[k] = convertTimestamps(k, item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = convertTimestamps(key, item)
+		}
+		return converted
+	case float64:
+		if strings.HasSuffix(key, "_at") {
+			seconds, fraction := math.Modf(v)
+			return time.Unix(int64(seconds), int64(fraction*1e9)).UTC().Format(time.RFC3339Nano)
+		}
+	}
+
+	return value
+}
+
+// secretFields are the names of JSON fields whose values are never logged.
+var secretFields = []string{"password", "consumer_secret", "token_secret", "private_key", "client_certificate"}
+
+// redactSecrets renders a JSON payload for logging, with the values of any
+// secretFields masked.
+func redactSecrets(payload []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return string(payload)
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return string(payload)
+	}
+
+	return string(redacted)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if contains(secretFields, key) && item != nil && item != "" {
+				v[key] = "*****"
+			} else {
+				v[key] = redactValue(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,129 @@
+package runscope
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	runscope "github.com/ewilde/go-runscope"
+	tfconfig "github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// testAPIRequest is a request received by the fake Runscope api, a JSON
+// object body is decoded into Body and a JSON array body into Items.
+type testAPIRequest struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+	Items  []interface{}
+}
+
+// testAPIHandler returns the status code and "data" element to respond to a
+// request to the fake Runscope api with.
+type testAPIHandler func(req *testAPIRequest) (int, interface{})
+
+// newTestAPIClient starts a fake Runscope api and returns a client configured
+// to use it, along with every request the api receives. Callers must close
+// the returned server.
+func newTestAPIClient(t *testing.T, handler testAPIHandler) (*runscope.Client, *[]*testAPIRequest, *httptest.Server) {
+	t.Helper()
+
+	requests := []*testAPIRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &testAPIRequest{Method: r.Method, Path: r.URL.Path}
+		body, _ := ioutil.ReadAll(r.Body)
+		if len(body) > 0 && body[0] == '[' {
+			if err := json.Unmarshal(body, &req.Items); err != nil {
+				t.Errorf("Unexpected request body %s %s: %s", r.Method, r.URL.Path, body)
+			}
+		} else if len(body) > 0 {
+			if err := json.Unmarshal(body, &req.Body); err != nil {
+				t.Errorf("Unexpected request body %s %s: %s", r.Method, r.URL.Path, body)
+			}
+		}
+		requests = append(requests, req)
+
+		status, data := handler(req)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":  data,
+			"error": map[string]interface{}{"status": status},
+		})
+	}))
+
+	return runscope.NewClient(server.URL, "token"), &requests, server
+}
+
+// testResourceApply plans and applies raw configuration against an existing
+// resource state, returning the resulting state.
+func testResourceApply(t *testing.T, r *schema.Resource, state *terraform.InstanceState,
+	raw map[string]interface{}, meta interface{}) *terraform.InstanceState {
+	t.Helper()
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := r.Diff(state, terraform.NewResourceConfig(c), meta)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	newState, err := r.Apply(state, diff, meta)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return newState
+}
+
+func TestAPIRequest(t *testing.T) {
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		if req.Path == "/buckets/missing" {
+			return http.StatusNotFound, nil
+		}
+
+		return http.StatusOK, map[string]interface{}{"name": req.Body["name"]}
+	})
+	defer server.Close()
+
+	data, err := apiRequest(client, "PUT", "/buckets/abc", map[string]string{"name": "renamed"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if name := data.(map[string]interface{})["name"]; name != "renamed" {
+		t.Fatalf("Expected name %s, actual %v", "renamed", name)
+	}
+
+	if len(*requests) != 1 || (*requests)[0].Method != "PUT" || (*requests)[0].Path != "/buckets/abc" {
+		t.Fatalf("Unexpected requests: %#v", *requests)
+	}
+
+	_, err = apiRequest(client, "GET", "/buckets/missing", nil)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("Expected 404 error, actual %v", err)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	payload := `{"auth":{"auth_type":"basic","username":"bob","password":"hunter2"},"steps":[{"auth":{"consumer_secret":"hunter2"}}]}`
+	redacted := redactSecrets([]byte(payload))
+	if strings.Contains(redacted, "hunter2") {
+		t.Fatalf("Expected secrets to be redacted, got %s", redacted)
+	}
+
+	if !strings.Contains(redacted, `"username":"bob"`) {
+		t.Fatalf("Expected username to be logged, got %s", redacted)
+	}
+
+	if redacted := redactSecrets([]byte("not json")); redacted != "not json" {
+		t.Fatalf("Expected payload that isn't JSON to be logged unchanged, got %s", redacted)
+	}
+}
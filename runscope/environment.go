@@ -0,0 +1,81 @@
+package runscope
+
+import (
+	"fmt"
+
+	runscope "github.com/ewilde/go-runscope"
+)
+
+// environmentDetails is a runscope environment with its script libraries.
+type environmentDetails struct {
+	*runscope.Environment
+	ScriptLibrary []string `json:"script_library"`
+	// ClientCertificate and ParentEnvironmentID shadow the fields of
+	// runscope.Environment so an empty value can be sent to remove them.
+	ClientCertificate   *string `json:"client_certificate,omitempty"`
+	ParentEnvironmentID *string `json:"parent_environment_id,omitempty"`
+}
+
+// parentEnvironmentID returns the id of the shared environment this one
+// inherits from, if any.
+func (e *environmentDetails) parentEnvironmentID() string {
+	if e.ParentEnvironmentID == nil {
+		return ""
+	}
+
+	return *e.ParentEnvironmentID
+}
+
+func newEnvironmentDetails() *environmentDetails {
+	return &environmentDetails{Environment: runscope.NewEnvironment(), ScriptLibrary: []string{}}
+}
+
+// environmentEndpoint returns the api endpoint for the environments of a
+// test, or the shared environments of a bucket when testID is empty.
+func environmentEndpoint(bucketID string, testID string) string {
+	if testID != "" {
+		return fmt.Sprintf("/buckets/%s/tests/%s/environments", bucketID, testID)
+	}
+
+	return fmt.Sprintf("/buckets/%s/environments", bucketID)
+}
+
+// createEnvironment creates a shared or test environment. See https://www.runscope.com/docs/api/environments#create
+func createEnvironment(client *runscope.Client, environment *environmentDetails, bucketID string, testID string) (*environmentDetails, error) {
+	data, err := apiRequest(client, "POST", environmentEndpoint(bucketID, testID), environment)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeEnvironment(data)
+}
+
+// readEnvironment reads a shared or test environment. See https://www.runscope.com/docs/api/environments#detail
+func readEnvironment(client *runscope.Client, environmentID string, bucketID string, testID string) (*environmentDetails, error) {
+	data, err := apiRequest(client, "GET", environmentEndpoint(bucketID, testID)+"/"+environmentID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeEnvironment(data)
+}
+
+// updateEnvironment updates a shared or test environment. See https://www.runscope.com/docs/api/environments#modify
+func updateEnvironment(client *runscope.Client, environment *environmentDetails, bucketID string, testID string) (*environmentDetails, error) {
+	data, err := apiRequest(client, "PUT", environmentEndpoint(bucketID, testID)+"/"+environment.ID, environment)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeEnvironment(data)
+}
+
+// decodeEnvironment converts the "data" of an environment response.
+func decodeEnvironment(data interface{}) (*environmentDetails, error) {
+	environment := newEnvironmentDetails()
+	if err := decodeAPIData(data, environment); err != nil {
+		return nil, fmt.Errorf("Unable to read environment: %s", err)
+	}
+
+	return environment, nil
+}
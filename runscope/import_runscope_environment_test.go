@@ -0,0 +1,104 @@
+package runscope
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccImportRunscopeEnvironment_shared(t *testing.T) {
+
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckEnvironmentDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccImportRunscopeEnvironmentConfig, teamID),
+			},
+			{
+				ResourceName:      "runscope_environment.shared",
+				ImportState:       true,
+				ImportStateIdFunc: testAccRunscopeEnvironmentImportStateIDFunc("runscope_environment.shared"),
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccImportRunscopeEnvironment_test(t *testing.T) {
+
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckEnvironmentDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccImportRunscopeEnvironmentConfig, teamID),
+			},
+			{
+				ResourceName:      "runscope_environment.test",
+				ImportState:       true,
+				ImportStateIdFunc: testAccRunscopeEnvironmentImportStateIDFunc("runscope_environment.test"),
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccRunscopeEnvironmentImportStateIDFunc(n string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return "", fmt.Errorf("Not found: %s", n)
+		}
+
+		if testID := rs.Primary.Attributes["test_id"]; testID != "" {
+			return fmt.Sprintf("%s/%s/%s", rs.Primary.Attributes["bucket_id"], testID, rs.Primary.ID), nil
+		}
+
+		return fmt.Sprintf("%s/%s", rs.Primary.Attributes["bucket_id"], rs.Primary.ID), nil
+	}
+}
+
+const testAccImportRunscopeEnvironmentConfig = `
+resource "runscope_environment" "shared" {
+  bucket_id = "${runscope_bucket.bucket.id}"
+  name      = "shared-environment"
+
+  initial_variables = {
+    var1 = "true"
+    var2 = "value2"
+  }
+
+  retry_on_failure = true
+}
+
+resource "runscope_environment" "test" {
+  bucket_id = "${runscope_bucket.bucket.id}"
+  test_id   = "${runscope_test.test.id}"
+  name      = "test-environment"
+
+  initial_variables = {
+    var1 = "true"
+  }
+}
+
+resource "runscope_test" "test" {
+  bucket_id = "${runscope_bucket.bucket.id}"
+  name = "runscope test"
+  description = "This is a test test..."
+}
+
+resource "runscope_bucket" "bucket" {
+  name = "terraform-provider-test"
+  team_uuid = "%s"
+}
+`
@@ -0,0 +1,66 @@
+package runscope
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccImportRunscopeSchedule(t *testing.T) {
+
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckScheduleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testRunscopeScheduleConfigA, teamID),
+			},
+			{
+				ResourceName:      "runscope_schedule.daily",
+				ImportState:       true,
+				ImportStateIdFunc: testAccRunscopeScheduleImportStateIDFunc("runscope_schedule.daily", "id"),
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccImportRunscopeSchedule_environment(t *testing.T) {
+
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckScheduleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testRunscopeScheduleConfigA, teamID),
+			},
+			{
+				ResourceName:      "runscope_schedule.daily",
+				ImportState:       true,
+				ImportStateIdFunc: testAccRunscopeScheduleImportStateIDFunc("runscope_schedule.daily", "environment_id"),
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccRunscopeScheduleImportStateIDFunc(n string, attribute string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return "", fmt.Errorf("Not found: %s", n)
+		}
+
+		return fmt.Sprintf("%s/%s/%s", rs.Primary.Attributes["bucket_id"],
+			rs.Primary.Attributes["test_id"], rs.Primary.Attributes[attribute]), nil
+	}
+}
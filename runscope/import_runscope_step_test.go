@@ -0,0 +1,107 @@
+package runscope
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccImportRunscopeStep(t *testing.T) {
+
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckStepDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccImportRunscopeStepConfig, teamID),
+			},
+			{
+				ResourceName:      "runscope_step.step",
+				ImportState:       true,
+				ImportStateIdFunc: testAccRunscopeStepImportStateIDFunc("runscope_step.step"),
+				ImportStateVerify: true,
+			},
+			{
+				Config:   fmt.Sprintf(testAccImportRunscopeStepConfig, teamID),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func testAccRunscopeStepImportStateIDFunc(n string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return "", fmt.Errorf("Not found: %s", n)
+		}
+
+		return fmt.Sprintf("%s/%s/%s",
+			rs.Primary.Attributes["bucket_id"], rs.Primary.Attributes["test_id"], rs.Primary.ID), nil
+	}
+}
+
+const testAccImportRunscopeStepConfig = `
+resource "runscope_step" "step" {
+  bucket_id      = "${runscope_bucket.bucket.id}"
+  test_id        = "${runscope_test.test.id}"
+  step_type      = "request"
+  note           = "Testing step import"
+  url            = "http://example.com"
+  method         = "GET"
+  headers {
+     header = "Accept"
+     value  = "application/json"
+  }
+  headers {
+     header = "Accept"
+     value  = "text/plain"
+  }
+  headers {
+     header = "X-Request-Id"
+     value  = "{{request_id}}"
+  }
+
+  auth {
+     auth_type = "basic"
+     username  = "user"
+     password  = "password1"
+  }
+
+  variables {
+     name     = "httpStatus"
+     source   = "response_status"
+  }
+
+  assertions {
+     source     = "response_json"
+     comparison = "equal"
+     value      = "c5baeb4a-2379-478a-9cda-1b671de77cf9"
+     property   = "data.id"
+  }
+
+  scripts = [
+    "log(\"script 1\");",
+  ]
+  before_scripts = [
+    "log(\"before script\");",
+  ]
+}
+
+resource "runscope_test" "test" {
+  bucket_id   = "${runscope_bucket.bucket.id}"
+  name        = "runscope test"
+  description = "This is a test test..."
+}
+
+resource "runscope_bucket" "bucket" {
+  name      = "terraform-provider-test"
+  team_uuid = "%s"
+}
+`
@@ -0,0 +1,43 @@
+package runscope
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccImportRunscopeTest(t *testing.T) {
+
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckTestDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testRunscopeTestConfigA, teamID),
+			},
+			{
+				ResourceName:      "runscope_test.test",
+				ImportState:       true,
+				ImportStateIdFunc: testAccRunscopeTestImportStateIDFunc("runscope_test.test"),
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccRunscopeTestImportStateIDFunc(n string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return "", fmt.Errorf("Not found: %s", n)
+		}
+
+		return fmt.Sprintf("%s/%s", rs.Primary.Attributes["bucket_id"], rs.Primary.ID), nil
+	}
+}
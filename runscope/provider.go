@@ -37,6 +37,7 @@ func Provider() terraform.ResourceProvider {
 			"runscope_environment": resourceRunscopeEnvironment(),
 			"runscope_schedule":    resourceRunscopeSchedule(),
 			"runscope_step":        resourceRunscopeStep(),
+			"runscope_test_steps":  resourceRunscopeTestSteps(),
 		},
 
 		ConfigureFunc: providerConfigure,
@@ -13,6 +13,7 @@ func resourceRunscopeBucket() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceBucketCreate,
 		Read:   resourceBucketRead,
+		Update: resourceBucketUpdate,
 		Delete: resourceBucketDelete,
 		Importer: &schema.ResourceImporter{
 			State: resourceBucketImport,
@@ -21,13 +22,42 @@ func resourceRunscopeBucket() *schema.Resource {
 			"name": {
 				Type:     schema.TypeString,
 				Required: true,
-				ForceNew: true,
+				ForceNew: false,
 			},
 			"team_uuid": {
 				Type:     schema.TypeString,
 				Required: true,
 				ForceNew: true,
 			},
+			"default": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"verify_ssl": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"auth_token": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"trigger_url": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"messages_url": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"tests_url": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"collections_url": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 		},
 	}
 }
@@ -74,9 +104,32 @@ func resourceBucketRead(d *schema.ResourceData, meta interface{}) error {
 
 	d.Set("name", bucket.Name)
 	d.Set("team_uuid", bucket.Team.ID)
+	d.Set("default", bucket.Default)
+	d.Set("verify_ssl", bucket.VerifySsl)
+	d.Set("auth_token", bucket.AuthToken)
+	d.Set("trigger_url", bucket.TriggerURL)
+	d.Set("messages_url", bucket.MessagesURL)
+	d.Set("tests_url", bucket.TestsURL)
+	d.Set("collections_url", bucket.CollectionsURL)
 	return nil
 }
 
+func resourceBucketUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*runscope.Client)
+
+	key := d.Id()
+	if d.HasChange("name") {
+		name := d.Get("name").(string)
+		log.Printf("[INFO] Renaming bucket with key: %s to name: %s", key, name)
+
+		if err := updateBucketName(client, key, name); err != nil {
+			return fmt.Errorf("Error updating bucket: %s", err)
+		}
+	}
+
+	return resourceBucketRead(d, meta)
+}
+
 func resourceBucketImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
 	key := d.Id()
 
@@ -120,3 +173,9 @@ func createBucketFromResourceData(d *schema.ResourceData) (*runscope.Bucket, err
 
 	return &bucket, nil
 }
+
+// updateBucketName renames a bucket.
+func updateBucketName(client *runscope.Client, key string, name string) error {
+	_, err := apiRequest(client, "PUT", fmt.Sprintf("/buckets/%s", key), map[string]string{"name": name})
+	return err
+}
@@ -34,6 +34,37 @@ func TestAccBucket_basic(t *testing.T) {
 	})
 }
 
+func TestAccBucket_rename(t *testing.T) {
+	var bucketKey string
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckBucketDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testRunscopeBucketConfigA, teamID),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBucketKey("runscope_bucket.bucket", &bucketKey, false),
+					resource.TestCheckResourceAttrSet("runscope_bucket.bucket", "trigger_url"),
+					resource.TestCheckResourceAttrSet("runscope_bucket.bucket", "messages_url"),
+					resource.TestCheckResourceAttrSet("runscope_bucket.bucket", "tests_url"),
+					resource.TestCheckResourceAttrSet("runscope_bucket.bucket", "auth_token"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testRunscopeBucketConfigRenamed, teamID),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBucketKey("runscope_bucket.bucket", &bucketKey, true),
+					resource.TestCheckResourceAttr(
+						"runscope_bucket.bucket", "name", "runscope-bucket-renamed"),
+				),
+			},
+		},
+	})
+}
+
 func init() {
 	resource.AddTestSweepers("runscope_bucket", &resource.Sweeper{
 		Name: "runscope_bucket",
@@ -113,8 +144,32 @@ func testAccCheckBucketExists(n string, bucket *runscope.Bucket) resource.TestCh
 	}
 }
 
+// testAccCheckBucketKey records the bucket key, or when compare is set
+// ensures it matches the one previously recorded.
+func testAccCheckBucketKey(n string, key *string, compare bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		if compare && rs.Primary.ID != *key {
+			return fmt.Errorf("Expected bucket %s to be renamed in place, got new bucket %s", *key, rs.Primary.ID)
+		}
+
+		*key = rs.Primary.ID
+		return nil
+	}
+}
+
 const testRunscopeBucketConfigA = `
 resource "runscope_bucket" "bucket" {
   name = "runscope-bucket"
   team_uuid = "%s"
 }`
+
+const testRunscopeBucketConfigRenamed = `
+resource "runscope_bucket" "bucket" {
+  name = "runscope-bucket-renamed"
+  team_uuid = "%s"
+}`
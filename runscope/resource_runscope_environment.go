@@ -2,6 +2,8 @@ package runscope
 
 import (
 	"bytes"
+	"crypto/tls"
+	"encoding/pem"
 	"fmt"
 	"log"
 	"strings"
@@ -19,6 +21,10 @@ func resourceRunscopeEnvironment() *schema.Resource {
 		Read:   resourceEnvironmentRead,
 		Update: resourceEnvironmentUpdate,
 		Delete: resourceEnvironmentDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceEnvironmentImport,
+		},
+		CustomizeDiff: resourceEnvironmentCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"bucket_id": {
@@ -37,9 +43,15 @@ func resourceRunscopeEnvironment() *schema.Resource {
 				ForceNew: false,
 			},
 			"script": {
-				Type:     schema.TypeString,
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         false,
+				DiffSuppressFunc: suppressEquivalentScriptDiffs,
+			},
+			"script_library": {
+				Type:     schema.TypeList,
 				Optional: true,
-				ForceNew: false,
+				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
 			"preserve_cookies": {
 				Type:     schema.TypeBool,
@@ -52,14 +64,25 @@ func resourceRunscopeEnvironment() *schema.Resource {
 				Optional: true,
 				ForceNew: false,
 			},
+			"parent_environment_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"effective_variables": {
+				Type:     schema.TypeMap,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+			},
 			"integrations": {
 				Type:     schema.TypeSet,
 				Optional: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
+			// Runscope picks a region when none are given.
 			"regions": {
 				Type:     schema.TypeSet,
 				Optional: true,
+				Computed: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
 			// TODO: rename this to remote_agent for UX
@@ -88,6 +111,12 @@ func resourceRunscopeEnvironment() *schema.Resource {
 				Optional: true,
 				Default:  true,
 			},
+			"client_certificate": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Sensitive:    true,
+				ValidateFunc: validateClientCertificate,
+			},
 			"webhooks": {
 				Type:     schema.TypeSet,
 				Optional: true,
@@ -111,9 +140,11 @@ func resourceRunscopeEnvironment() *schema.Resource {
 								"all", "failures", "threshold", "switch",
 							}, false),
 						},
+						// Only used when notify_on is "threshold".
 						"notify_threshold": {
-							Type:     schema.TypeInt,
-							Required: true,
+							Type:             schema.TypeInt,
+							Optional:         true,
+							DiffSuppressFunc: suppressUnusedNotifyThreshold,
 						},
 						// TODO: rename this to "recipient"
 						"recipients": {
@@ -124,10 +155,13 @@ func resourceRunscopeEnvironment() *schema.Resource {
 									"name": {
 										Type:     schema.TypeString,
 										Optional: true,
+										Computed: true,
 									},
+									// Looked up from the email address when not given.
 									"id": {
 										Type:     schema.TypeString,
 										Optional: true,
+										Computed: true,
 									},
 									"email": {
 										Type:     schema.TypeString,
@@ -139,7 +173,9 @@ func resourceRunscopeEnvironment() *schema.Resource {
 						},
 					},
 				},
+				// Runscope returns default email settings when none are given.
 				Optional: true,
+				Computed: true,
 			},
 		},
 	}
@@ -157,16 +193,13 @@ func resourceEnvironmentCreate(d *schema.ResourceData, meta interface{}) error {
 	}
 	log.Printf("[DEBUG] environment create: %#v", environment)
 
-	var createdEnvironment *runscope.Environment
 	bucketID := d.Get("bucket_id").(string)
-
-	if testID, ok := d.GetOk("test_id"); ok {
-		createdEnvironment, err = client.CreateTestEnvironment(environment,
-			&runscope.Test{ID: testID.(string), Bucket: &runscope.Bucket{Key: bucketID}})
-	} else {
-		createdEnvironment, err = client.CreateSharedEnvironment(environment,
-			&runscope.Bucket{Key: bucketID})
+	testID := d.Get("test_id").(string)
+	if err := resolveRecipients(client, bucketID, environment.EmailSettings); err != nil {
+		return fmt.Errorf("Failed to create environment: %s", err)
 	}
+
+	createdEnvironment, err := createEnvironment(client, environment, bucketID, testID)
 	if err != nil {
 		return fmt.Errorf("Failed to create environment: %s", err)
 	}
@@ -185,16 +218,9 @@ func resourceEnvironmentRead(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Failed to read environment from resource data: %s", err)
 	}
 
-	var environment *runscope.Environment
 	bucketID := d.Get("bucket_id").(string)
-	if testID, ok := d.GetOk("test_id"); ok {
-		environment, err = client.ReadTestEnvironment(
-			environmentFromResource, &runscope.Test{ID: testID.(string), Bucket: &runscope.Bucket{Key: bucketID}})
-	} else {
-		environment, err = client.ReadSharedEnvironment(
-			environmentFromResource, &runscope.Bucket{Key: bucketID})
-	}
-
+	testID := d.Get("test_id").(string)
+	environment, err := readEnvironment(client, environmentFromResource.ID, bucketID, testID)
 	if err != nil {
 		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "403") {
 			d.SetId("")
@@ -208,13 +234,56 @@ func resourceEnvironmentRead(d *schema.ResourceData, meta interface{}) error {
 	d.Set("test_id", d.Get("test_id").(string))
 	d.Set("name", environment.Name)
 	d.Set("script", environment.Script)
+	d.Set("script_library", environment.ScriptLibrary)
 	d.Set("preserve_cookies", environment.PreserveCookies)
 	d.Set("initial_variables", environment.InitialVariables)
-	d.Set("integrations", readIntegrations(environment.Integrations))
+	d.Set("parent_environment_id", environment.parentEnvironmentID())
+
+	effectiveVariables, err := readEffectiveVariables(client, environment, bucketID)
+	if err != nil {
+		return fmt.Errorf("Couldn't read variables of parent environment: %s", err)
+	}
+	d.Set("effective_variables", effectiveVariables)
+	if err := d.Set("integrations", readIntegrations(environment.Integrations)); err != nil {
+		return fmt.Errorf("Error setting integrations: %s", err)
+	}
+	if err := d.Set("regions", environment.Regions); err != nil {
+		return fmt.Errorf("Error setting regions: %s", err)
+	}
+	if err := d.Set("remote_agents", readRemoteAgents(environment.RemoteAgents)); err != nil {
+		return fmt.Errorf("Error setting remote_agents: %s", err)
+	}
 	d.Set("retry_on_failure", environment.RetryOnFailure)
 	d.Set("verify_ssl", environment.VerifySsl)
+	// The certificate isn't always returned, only replace the configured one
+	// when it is.
+	if environment.ClientCertificate != nil && *environment.ClientCertificate != "" {
+		d.Set("client_certificate", *environment.ClientCertificate)
+	}
 	d.Set("webhooks", environment.WebHooks)
-	d.Set("emails", readEmail(environment.EmailSettings))
+	if err := d.Set("emails", readEmail(environment.EmailSettings)); err != nil {
+		return fmt.Errorf("Error setting emails: %s", err)
+	}
+	return nil
+}
+
+func resourceEnvironmentCustomizeDiff(diff *schema.ResourceDiff, meta interface{}) error {
+	if diff.Get("parent_environment_id").(string) != "" && diff.Get("test_id").(string) == "" {
+		return fmt.Errorf("parent_environment_id can only be set for test environments, test_id must be set")
+	}
+
+	if err := validateEmails(diff.Get("emails").([]interface{})); err != nil {
+		return err
+	}
+
+	if err := fillRecipientEmails(diff); err != nil {
+		return err
+	}
+
+	if diff.HasChange("initial_variables") || diff.HasChange("parent_environment_id") {
+		return diff.SetNewComputed("effective_variables")
+	}
+
 	return nil
 }
 
@@ -227,30 +296,63 @@ func resourceEnvironmentUpdate(d *schema.ResourceData, meta interface{}) error {
 
 	if d.HasChange("name") ||
 		d.HasChange("script") ||
+		d.HasChange("script_library") ||
 		d.HasChange("preserve_cookies") ||
 		d.HasChange("initial_variables") ||
+		d.HasChange("parent_environment_id") ||
 		d.HasChange("integrations") ||
 		d.HasChange("regions") ||
 		d.HasChange("remote_agents") ||
 		d.HasChange("retry_on_failure") ||
 		d.HasChange("verify_ssl") ||
+		d.HasChange("client_certificate") ||
 		d.HasChange("webhooks") ||
 		d.HasChange("emails") {
 		client := meta.(*runscope.Client)
 		bucketID := d.Get("bucket_id").(string)
-		if testID, ok := d.GetOk("test_id"); ok {
-			_, err = client.UpdateTestEnvironment(
-				environment, &runscope.Test{ID: testID.(string), Bucket: &runscope.Bucket{Key: bucketID}})
-		} else {
-			_, err = client.UpdateSharedEnvironment(
-				environment, &runscope.Bucket{Key: bucketID})
+		testID := d.Get("test_id").(string)
+		if err := resolveRecipients(client, bucketID, environment.EmailSettings); err != nil {
+			return fmt.Errorf("Error updating environment: %s", err)
 		}
+
+		_, err = updateEnvironment(client, environment, bucketID, testID)
 		if err != nil {
 			return fmt.Errorf("Error updating environment: %s", err)
 		}
 	}
 
-	return nil
+	return resourceEnvironmentRead(d, meta)
+}
+
+func resourceEnvironmentImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.Split(d.Id(), "/")
+
+	switch {
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		d.Set("bucket_id", parts[0])
+		d.SetId(parts[1])
+	case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+		d.Set("bucket_id", parts[0])
+		d.Set("test_id", parts[1])
+		d.SetId(parts[2])
+	default:
+		return nil, fmt.Errorf("Unexpected format of ID (%s), expected bucket_key/environment_id "+
+			"or bucket_key/test_id/environment_id", d.Id())
+	}
+
+	environmentID := d.Id()
+	err := resourceEnvironmentRead(d, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Id() == "" {
+		return nil, fmt.Errorf("Couldn't find environment: %s", environmentID)
+	}
+
+	results := []*schema.ResourceData{d}
+
+	return results, nil
 }
 
 func resourceEnvironmentDelete(d *schema.ResourceData, meta interface{}) error {
@@ -266,12 +368,12 @@ func resourceEnvironmentDelete(d *schema.ResourceData, meta interface{}) error {
 		log.Printf("[INFO] Deleting test environment with id: %s name: %s, from test %s",
 			environmentFromResource.ID, environmentFromResource.Name, testID.(string))
 		err = client.DeleteEnvironment(
-			environmentFromResource, &runscope.Bucket{Key: bucketID})
+			environmentFromResource.Environment, &runscope.Bucket{Key: bucketID})
 	} else {
 		log.Printf("[INFO] Deleting shared environment with id: %s name: %s",
 			environmentFromResource.ID, environmentFromResource.Name)
 		err = client.DeleteEnvironment(
-			environmentFromResource, &runscope.Bucket{Key: bucketID})
+			environmentFromResource.Environment, &runscope.Bucket{Key: bucketID})
 	}
 
 	if err != nil {
@@ -281,9 +383,36 @@ func resourceEnvironmentDelete(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
-func createEnvironmentFromResourceData(d *schema.ResourceData) (*runscope.Environment, error) {
+// readEffectiveVariables merges the initial variables of an environment with
+// those it inherits from its parent shared environment, the environment's
+// own variables take precedence.
+func readEffectiveVariables(client *runscope.Client, environment *environmentDetails, bucketID string) (map[string]string, error) {
+	variables := map[string]string{}
+	if parentID := environment.parentEnvironmentID(); parentID != "" {
+		parent, err := readEnvironment(client, parentID, bucketID, "")
+		switch {
+		case err != nil && strings.Contains(err.Error(), "404"):
+			log.Printf("[WARN] Parent environment %s of environment %s not found",
+				parentID, environment.ID)
+		case err != nil:
+			return nil, err
+		default:
+			for name, value := range parent.InitialVariables {
+				variables[name] = value
+			}
+		}
+	}
 
-	environment := runscope.NewEnvironment()
+	for name, value := range environment.InitialVariables {
+		variables[name] = value
+	}
+
+	return variables, nil
+}
+
+func createEnvironmentFromResourceData(d *schema.ResourceData) (*environmentDetails, error) {
+
+	environment := newEnvironmentDetails()
 	environment.ID = d.Id()
 
 	if attr, ok := d.GetOk("name"); ok {
@@ -298,6 +427,10 @@ func createEnvironmentFromResourceData(d *schema.ResourceData) (*runscope.Enviro
 		environment.Script = attr.(string)
 	}
 
+	if attr, ok := d.GetOk("script_library"); ok {
+		environment.ScriptLibrary = expandStringList(attr.([]interface{}))
+	}
+
 	if attr, ok := d.GetOk("preserve_cookies"); ok {
 		environment.PreserveCookies = attr.(bool)
 	}
@@ -312,6 +445,15 @@ func createEnvironmentFromResourceData(d *schema.ResourceData) (*runscope.Enviro
 		environment.InitialVariables = variables
 	}
 
+	if attr, ok := d.GetOk("parent_environment_id"); ok {
+		parentID := attr.(string)
+		environment.ParentEnvironmentID = &parentID
+	} else if d.HasChange("parent_environment_id") {
+		// The parent was removed from the config, detach the environment.
+		parentID := ""
+		environment.ParentEnvironmentID = &parentID
+	}
+
 	if attr, ok := d.GetOk("integrations"); ok {
 		integrations := []*runscope.EnvironmentIntegration{}
 		items := attr.(*schema.Set)
@@ -361,6 +503,15 @@ func createEnvironmentFromResourceData(d *schema.ResourceData) (*runscope.Enviro
 		environment.VerifySsl = attr
 	}
 
+	if attr, ok := d.GetOk("client_certificate"); ok {
+		certificate := attr.(string)
+		environment.ClientCertificate = &certificate
+	} else if d.HasChange("client_certificate") {
+		// The certificate was removed from the config, remove it remotely too.
+		certificate := ""
+		environment.ClientCertificate = &certificate
+	}
+
 	if attr, ok := d.GetOk("webhooks"); ok {
 		webhooks := []string{}
 		items := attr.(*schema.Set)
@@ -372,14 +523,15 @@ func createEnvironmentFromResourceData(d *schema.ResourceData) (*runscope.Enviro
 		environment.WebHooks = webhooks
 	}
 
-	if attr, ok := d.GetOk("emails"); ok {
+	if attr, ok := d.GetOk("emails"); ok && len(attr.([]interface{})) > 0 && attr.([]interface{})[0] != nil {
 		contacts := []*runscope.Contact{}
-		tmp := attr.([]interface{})
-		items := tmp[0].(map[string]interface{})
+		items := attr.([]interface{})[0].(map[string]interface{})
 		emailSettings := runscope.EmailSettings{
-			NotifyAll:       items["notify_all"].(bool),
-			NotifyOn:        items["notify_on"].(string),
-			NotifyThreshold: items["notify_threshold"].(int),
+			NotifyAll: items["notify_all"].(bool),
+			NotifyOn:  items["notify_on"].(string),
+		}
+		if emailSettings.NotifyOn == "threshold" {
+			emailSettings.NotifyThreshold = items["notify_threshold"].(int)
 		}
 
 		for _, x := range items["recipients"].(*schema.Set).List() {
@@ -400,23 +552,32 @@ func createEnvironmentFromResourceData(d *schema.ResourceData) (*runscope.Enviro
 	return environment, nil
 }
 
-func readIntegrations(integrations []*runscope.EnvironmentIntegration) []map[string]interface{} {
-	result := make([]map[string]interface{}, 0, len(integrations))
+func readIntegrations(integrations []*runscope.EnvironmentIntegration) []string {
+	result := make([]string, 0, len(integrations))
 	for _, integration := range integrations {
+		result = append(result, integration.ID)
+	}
 
-		item := map[string]interface{}{
-			"id":               integration.ID,
-			"integration_type": integration.IntegrationType,
-			"description":      integration.Description,
-		}
+	return result
+}
 
-		result = append(result, item)
+func readRemoteAgents(remoteAgents []*runscope.LocalMachine) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(remoteAgents))
+	for _, remoteAgent := range remoteAgents {
+		result = append(result, map[string]interface{}{
+			"name": remoteAgent.Name,
+			"uuid": remoteAgent.UUID,
+		})
 	}
 
 	return result
 }
 
-func readEmail(emailSettings *runscope.EmailSettings) interface{} {
+func readEmail(emailSettings *runscope.EmailSettings) []interface{} {
+	if emailSettings == nil {
+		return []interface{}{}
+	}
+
 	resultRecipients := make([]interface{}, 0, 4)
 
 	for _, recipient := range emailSettings.Recipients {
@@ -429,21 +590,174 @@ func readEmail(emailSettings *runscope.EmailSettings) interface{} {
 	}
 
 	item := map[string]interface{}{
-		"notify_all":       emailSettings.NotifyAll,
-		"notify_on":        emailSettings.NotifyOn,
-		"notify_threshold": emailSettings.NotifyThreshold,
-		"recipients":       resultRecipients,
+		"notify_all": emailSettings.NotifyAll,
+		"notify_on":  emailSettings.NotifyOn,
+		"recipients": resultRecipients,
+	}
+
+	if emailSettings.NotifyOn == "threshold" {
+		item["notify_threshold"] = emailSettings.NotifyThreshold
+	}
+
+	return []interface{}{item}
+}
+
+// validateEmails checks the notify_threshold is given with
+// notify_on = "threshold", and that every recipient can be found.
+func validateEmails(emails []interface{}) error {
+	if len(emails) == 0 || emails[0] == nil {
+		return nil
+	}
+
+	email := emails[0].(map[string]interface{})
+	notifyOn := email["notify_on"].(string)
+	notifyThreshold := email["notify_threshold"].(int)
+	if notifyOn == "threshold" && notifyThreshold < 1 {
+		return fmt.Errorf("emails: notify_threshold must be at least 1 when notify_on is \"threshold\"")
+	}
+	if notifyOn != "threshold" && notifyThreshold != 0 {
+		// This used to be required, so older configs still set it.
+		log.Printf("[WARN] emails: notify_threshold is ignored when notify_on is %q, "+
+			"it will be rejected in a future release", notifyOn)
+	}
+
+	recipients, _ := email["recipients"].(*schema.Set)
+	if recipients == nil {
+		return nil
+	}
+
+	for _, x := range recipients.List() {
+		recipient := x.(map[string]interface{})
+		if recipient["id"].(string) == "" && recipient["email"].(string) == "" {
+			return fmt.Errorf("emails: recipients must have an id or an email")
+		}
+	}
+
+	return nil
+}
+
+// suppressUnusedNotifyThreshold ignores changes to notify_threshold while
+// notify_on isn't "threshold", as it isn't sent to Runscope.
+func suppressUnusedNotifyThreshold(k, old, new string, d *schema.ResourceData) bool {
+	return d.Get(strings.TrimSuffix(k, "notify_threshold")+"notify_on").(string) != "threshold"
+}
+
+// fillRecipientEmails fills in the email address of recipients configured by
+// id alone from the recipients already in state, so they hash the same as
+// the recipients read back from Runscope.
+func fillRecipientEmails(diff *schema.ResourceDiff) error {
+	o, n := diff.GetChange("emails")
+	oldEmails, newEmails := o.([]interface{}), n.([]interface{})
+	if len(oldEmails) == 0 || oldEmails[0] == nil || len(newEmails) == 0 || newEmails[0] == nil {
+		return nil
+	}
+
+	known := map[string]map[string]interface{}{}
+	for _, x := range oldEmails[0].(map[string]interface{})["recipients"].(*schema.Set).List() {
+		recipient := x.(map[string]interface{})
+		known[recipient["id"].(string)] = recipient
+	}
+
+	email := map[string]interface{}{}
+	for k, v := range newEmails[0].(map[string]interface{}) {
+		email[k] = v
 	}
 
-	return item
+	filled := false
+	recipients := []interface{}{}
+	for _, x := range email["recipients"].(*schema.Set).List() {
+		recipient := x.(map[string]interface{})
+		if previous, ok := known[recipient["id"].(string)]; ok && recipient["email"].(string) == "" {
+			recipient = map[string]interface{}{
+				"id":    recipient["id"],
+				"name":  previous["name"],
+				"email": previous["email"],
+			}
+			filled = true
+		}
 
+		recipients = append(recipients, recipient)
+	}
+
+	if !filled {
+		return nil
+	}
+
+	email["recipients"] = recipients
+	return diff.SetNew("emails", []interface{}{email})
 }
 
+// resolveRecipients looks up the team member id of recipients only given by
+// their email address.
+func resolveRecipients(client *runscope.Client, bucketID string, emailSettings *runscope.EmailSettings) error {
+	if emailSettings == nil {
+		return nil
+	}
+
+	var people []*runscope.People
+	for _, recipient := range emailSettings.Recipients {
+		if recipient.ID != "" {
+			continue
+		}
+
+		if people == nil {
+			bucket, err := client.ReadBucket(bucketID)
+			if err != nil {
+				return fmt.Errorf("Couldn't find bucket: %s", err)
+			}
+
+			people, err = client.ListPeople(bucket.Team.ID)
+			if err != nil {
+				return fmt.Errorf("Couldn't list people for team %s: %s", bucket.Team.ID, err)
+			}
+		}
+
+		for _, person := range people {
+			if strings.EqualFold(person.Email, recipient.Email) {
+				recipient.ID = person.ID
+				break
+			}
+		}
+
+		if recipient.ID == "" {
+			return fmt.Errorf("Unable to locate team member with email %s", recipient.Email)
+		}
+	}
+
+	return nil
+}
+
+// recipientsHash identifies recipients by email address, falling back to
+// their id, so a recipient given by email alone matches the one read back.
+// Recipients given by id alone have their email filled in by
+// fillRecipientEmails.
 func recipientsHash(v interface{}) int {
 	var buf bytes.Buffer
 	m := v.(map[string]interface{})
-	buf.WriteString(fmt.Sprintf("%s-", m["name"].(string)))
-	buf.WriteString(fmt.Sprintf("%s-", m["id"].(string)))
-	buf.WriteString(fmt.Sprintf("%s-", m["email"].(string)))
+	if email := m["email"].(string); email != "" {
+		buf.WriteString(fmt.Sprintf("%s-", strings.ToLower(email)))
+	} else {
+		buf.WriteString(fmt.Sprintf("%s-", m["id"].(string)))
+	}
 	return hashcode.String(buf.String())
 }
+
+// validateClientCertificate ensures a client certificate is a PEM encoded
+// certificate followed by the private key that matches it.
+func validateClientCertificate(v interface{}, k string) (ws []string, errors []error) {
+	value := v.(string)
+	if value == "" {
+		return
+	}
+
+	if block, _ := pem.Decode([]byte(value)); block == nil {
+		errors = append(errors, fmt.Errorf("%q must be PEM encoded", k))
+		return
+	}
+
+	if _, err := tls.X509KeyPair([]byte(value), []byte(value)); err != nil {
+		errors = append(errors, fmt.Errorf("%q must contain a PEM certificate and its matching private key: %s", k, err))
+	}
+
+	return
+}
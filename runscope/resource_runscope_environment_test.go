@@ -1,13 +1,26 @@
 package runscope
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"net/http"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	runscope "github.com/ewilde/go-runscope"
+	tfconfig "github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -33,24 +46,54 @@ func TestAccEnvironment_basic(t *testing.T) {
 
 func TestAccEnvironment_emails(t *testing.T) {
 	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+	// The person who created the test is always a member of the team.
+	recipient := `id = "${runscope_test.test.created_by.0.id}"`
 	resource.Test(t, resource.TestCase{
 		PreCheck:     func() { testAccPreCheck(t) },
 		Providers:    testAccProviders,
 		CheckDestroy: testAccCheckEnvironmentDestroy,
 		Steps: []resource.TestStep{
 			{
-				Config: fmt.Sprintf(testRunscopeEnvrionmentConfigWithEmail, teamID, teamID),
+				Config: fmt.Sprintf(testRunscopeEnvrionmentConfigWithEmail, recipient, teamID, teamID),
 				Check: resource.ComposeTestCheckFunc(
 					testAccCheckEnvironmentExists("runscope_environment.environmentA"),
 					resource.TestCheckResourceAttr("runscope_environment.environmentA", "name", "test-environment"),
 					resource.TestCheckResourceAttr("runscope_environment.environmentA", "verify_ssl", "true"),
 					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.#", "1"),
 					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.0.notify_all", "true"),
-					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.0.notify_on", "all"),
+					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.0.notify_on", "threshold"),
 					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.0.notify_threshold", "1"),
-					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.0.recipients.#", "2"),
-					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.0.recipients.769210288.name", "bob"),
-					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.0.recipients.769210288.email", "bob@gmail.com"),
+					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.0.recipients.#", "1"),
+					testAccCheckEnvironmentRecipient("runscope_environment.environmentA", "runscope_test.test"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccEnvironment_emailRecipientByEmail(t *testing.T) {
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+	recipientEmail := os.Getenv("RUNSCOPE_RECIPIENT_EMAIL")
+	if recipientEmail == "" {
+		t.Skip("RUNSCOPE_RECIPIENT_EMAIL must be set to the email address of a team member")
+	}
+
+	recipient := fmt.Sprintf("emails.0.recipients.%d", recipientsHash(map[string]interface{}{
+		"name": "", "id": "", "email": recipientEmail,
+	}))
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckEnvironmentDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testRunscopeEnvrionmentConfigWithEmail, fmt.Sprintf("email = %q", recipientEmail), teamID, teamID),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckEnvironmentExists("runscope_environment.environmentA"),
+					resource.TestCheckResourceAttr("runscope_environment.environmentA", "emails.0.recipients.#", "1"),
+					resource.TestCheckResourceAttr("runscope_environment.environmentA", recipient+".email", recipientEmail),
+					resource.TestCheckResourceAttrSet("runscope_environment.environmentA", recipient+".id"),
+					resource.TestCheckResourceAttrSet("runscope_environment.environmentA", recipient+".name"),
 				),
 			},
 		},
@@ -77,6 +120,110 @@ func TestAccEnvironment_do_not_verify_ssl(t *testing.T) {
 	})
 }
 
+func TestAccEnvironment_remoteChanges(t *testing.T) {
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+	config := fmt.Sprintf(testRunscopeEnvrionmentConfigA, teamID, teamID)
+	edits := map[string]func(e *environmentDetails){
+		"name":              func(e *environmentDetails) { e.Name = "renamed-environment" },
+		"initial_variables": func(e *environmentDetails) { e.InitialVariables["var1"] = "edited" },
+		"integrations":      func(e *environmentDetails) { e.Integrations = []*runscope.EnvironmentIntegration{} },
+		"regions":           func(e *environmentDetails) { e.Regions = []string{"us1"} },
+		"remote_agents":     func(e *environmentDetails) { e.RemoteAgents = []*runscope.LocalMachine{} },
+		"retry_on_failure":  func(e *environmentDetails) { e.RetryOnFailure = false },
+		"verify_ssl":        func(e *environmentDetails) { e.VerifySsl = false },
+		"webhooks":          func(e *environmentDetails) { e.WebHooks = []string{} },
+		"script":            func(e *environmentDetails) { e.Script = "var edited = true;" },
+		"preserve_cookies":  func(e *environmentDetails) { e.PreserveCookies = !e.PreserveCookies },
+	}
+
+	var environmentID, bucketID string
+	steps := []resource.TestStep{
+		{
+			Config: config,
+			Check: resource.ComposeTestCheckFunc(
+				testAccCheckEnvironmentExists("runscope_environment.environmentA"),
+				func(s *terraform.State) error {
+					rs := s.RootModule().Resources["runscope_environment.environmentA"]
+					environmentID = rs.Primary.ID
+					bucketID = rs.Primary.Attributes["bucket_id"]
+					return nil
+				}),
+		},
+	}
+	for _, edit := range edits {
+		steps = append(steps,
+			resource.TestStep{
+				PreConfig:          testAccEditEnvironment(t, &environmentID, &bucketID, edit),
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			// Put the environment back before the next edit.
+			resource.TestStep{
+				Config: config,
+			})
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckEnvironmentDestroy,
+		Steps:        steps,
+	})
+}
+
+// testAccEditEnvironment changes a shared environment outside of terraform.
+func testAccEditEnvironment(t *testing.T, environmentID *string, bucketID *string, edit func(e *environmentDetails)) func() {
+	return func() {
+		client := testAccProvider.Meta().(*runscope.Client)
+		environment, err := readEnvironment(client, *environmentID, *bucketID, "")
+		if err != nil {
+			t.Fatalf("Couldn't find environment %s: %s", *environmentID, err)
+		}
+
+		edit(environment)
+		environment.ExportedAt = nil
+		if _, err := updateEnvironment(client, environment, *bucketID, ""); err != nil {
+			t.Fatalf("Error updating environment %s: %s", *environmentID, err)
+		}
+	}
+}
+
+// testAccCheckEnvironmentRecipient checks the only email recipient of an
+// environment is the person who created a test.
+func testAccCheckEnvironmentRecipient(n string, testN string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		test, ok := s.RootModule().Resources[testN]
+		if !ok {
+			return fmt.Errorf("Not found: %s", testN)
+		}
+
+		creatorID := test.Primary.Attributes["created_by.0.id"]
+		for key, value := range rs.Primary.Attributes {
+			if !strings.HasPrefix(key, "emails.0.recipients.") || !strings.HasSuffix(key, ".id") {
+				continue
+			}
+
+			if value != creatorID {
+				return fmt.Errorf("Expected recipient %s, actual %s", creatorID, value)
+			}
+
+			if email := rs.Primary.Attributes[strings.TrimSuffix(key, ".id")+".email"]; email == "" {
+				return fmt.Errorf("Expected the email of recipient %s to be read back", value)
+			}
+
+			return nil
+		}
+
+		return fmt.Errorf("No recipients found")
+	}
+}
+
 func testAccCheckEnvironmentDestroy(s *terraform.State) error {
 	client := testAccProvider.Meta().(*runscope.Client)
 
@@ -238,18 +385,13 @@ resource "runscope_environment" "environmentA" {
 	webhooks = ["https://example.com"]
 	emails {
 	  notify_all       = true
-      notify_on        = "all"
+      notify_on        = "threshold"
       notify_threshold = 1
 
       recipients {
-      		name = "marek"
-      		email = "marekpastierik15@gmail.com"
-      	}
-      	recipients {
-      		name = "bob"
-      		email = "bob@gmail.com"
+      		%s
       	}
-      
+
 	}
 }
 
@@ -313,3 +455,768 @@ data "runscope_integration" "slack" {
   type = "slack"
 }
 `
+
+func TestResourceEnvironmentCreate_scriptLibrary(t *testing.T) {
+	environment := map[string]interface{}{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/environments":
+			environment = req.Body
+			environment["id"] = "environment-id"
+			environment["exported_at"] = 1494023235
+			// Runscope strips the trailing newline from scripts.
+			environment["script"] = strings.TrimSpace(environment["script"].(string))
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/environments/environment-id":
+			return http.StatusOK, environment
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id":      "bucket-key",
+		"name":           "shared",
+		"script":         "var a = 1;  \r\n",
+		"script_library": []interface{}{"library-a", "library-b"},
+	}
+
+	state := testResourceApply(t, resourceRunscopeEnvironment(), nil, raw, client)
+
+	if libraries := (*requests)[0].Body["script_library"]; !reflect.DeepEqual(libraries, []interface{}{"library-a", "library-b"}) {
+		t.Fatalf("Expected script libraries to be sent, got %#v", libraries)
+	}
+
+	if state.Attributes["script_library.#"] != "2" || state.Attributes["script_library.1"] != "library-b" {
+		t.Fatalf("Unexpected script libraries in state %#v", state.Attributes)
+	}
+
+	// Environments without email settings can still be read.
+	if state.Attributes["emails.#"] != "0" {
+		t.Fatalf("Expected no email settings in state %#v", state.Attributes)
+	}
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := resourceRunscopeEnvironment().Diff(state, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !diff.Empty() {
+		t.Fatalf("Expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestValidateClientCertificate(t *testing.T) {
+	cert, key := testClientCertificate(t)
+	_, otherKey := testClientCertificate(t)
+
+	cases := []struct {
+		Value     string
+		ExpectErr bool
+	}{
+		{Value: cert + key},
+		{Value: key + cert},
+		{Value: ""},
+		{Value: "not a certificate", ExpectErr: true},
+		{Value: cert, ExpectErr: true},
+		{Value: cert + otherKey, ExpectErr: true},
+	}
+
+	for i, tc := range cases {
+		_, errors := validateClientCertificate(tc.Value, "client_certificate")
+		if tc.ExpectErr && len(errors) == 0 {
+			t.Fatalf("%d: expected error", i)
+		}
+		if !tc.ExpectErr && len(errors) != 0 {
+			t.Fatalf("%d: unexpected errors: %v", i, errors)
+		}
+	}
+}
+
+// testClientCertificate returns a PEM encoded self signed certificate and its
+// private key.
+func testClientCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "runscope-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return string(cert), string(keyPem)
+}
+
+func TestResourceEnvironmentCreate_parentEnvironment(t *testing.T) {
+	environment := map[string]interface{}{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/tests/test-id/environments":
+			environment = req.Body
+			environment["id"] = "environment-id"
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/tests/test-id/environments/environment-id":
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/environments/shared-id":
+			return http.StatusOK, map[string]interface{}{
+				"id":                "shared-id",
+				"initial_variables": map[string]interface{}{"base_url": "https://example.com", "user": "shared"},
+			}
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	state := testResourceApply(t, resourceRunscopeEnvironment(), nil, map[string]interface{}{
+		"bucket_id":             "bucket-key",
+		"test_id":               "test-id",
+		"name":                  "test environment",
+		"parent_environment_id": "shared-id",
+		"initial_variables":     map[string]interface{}{"user": "test"},
+	}, client)
+
+	if parent := (*requests)[0].Body["parent_environment_id"]; parent != "shared-id" {
+		t.Fatalf("Expected parent environment %s to be sent, got %v", "shared-id", parent)
+	}
+
+	expected := map[string]string{
+		"parent_environment_id":        "shared-id",
+		"effective_variables.%":        "2",
+		"effective_variables.base_url": "https://example.com",
+		"effective_variables.user":     "test",
+	}
+
+	for key, value := range expected {
+		if state.Attributes[key] != value {
+			t.Errorf("Expected %s to be %s, actual %s", key, value, state.Attributes[key])
+		}
+	}
+
+	c, err := tfconfig.NewRawConfig(map[string]interface{}{
+		"bucket_id":             "bucket-key",
+		"name":                  "shared environment",
+		"parent_environment_id": "shared-id",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := resourceRunscopeEnvironment().Diff(nil, terraform.NewResourceConfig(c), client); err == nil {
+		t.Fatalf("Expected error setting parent_environment_id on a shared environment")
+	}
+}
+
+func TestResourceEnvironmentUpdate_parentEnvironment(t *testing.T) {
+	environment := map[string]interface{}{}
+	client, _, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/tests/test-id/environments":
+			environment = map[string]interface{}{"id": "environment-id"}
+			for k, v := range req.Body {
+				environment[k] = v
+			}
+			return http.StatusOK, environment
+		case req.Method == "PUT" && req.Path == "/buckets/bucket-key/tests/test-id/environments/environment-id":
+			for k, v := range req.Body {
+				environment[k] = v
+			}
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/tests/test-id/environments/environment-id":
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/environments/shared-id":
+			return http.StatusOK, map[string]interface{}{
+				"id":                "shared-id",
+				"initial_variables": map[string]interface{}{"base_url": "https://example.com", "user": "shared"},
+			}
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id":             "bucket-key",
+		"test_id":               "test-id",
+		"name":                  "test environment",
+		"parent_environment_id": "shared-id",
+		"initial_variables":     map[string]interface{}{"user": "test"},
+	}
+	state := testResourceApply(t, resourceRunscopeEnvironment(), nil, raw, client)
+
+	raw["initial_variables"] = map[string]interface{}{"user": "updated", "token": "abc"}
+	state = testResourceApply(t, resourceRunscopeEnvironment(), state, raw, client)
+
+	expected := map[string]string{
+		"effective_variables.%":        "3",
+		"effective_variables.base_url": "https://example.com",
+		"effective_variables.user":     "updated",
+		"effective_variables.token":    "abc",
+	}
+
+	for key, value := range expected {
+		if state.Attributes[key] != value {
+			t.Errorf("Expected %s to be %s, actual %s", key, value, state.Attributes[key])
+		}
+	}
+}
+
+func TestResourceEnvironmentRead_remoteChanges(t *testing.T) {
+	var environment map[string]interface{}
+	client, _, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/environments":
+			environment = req.Body
+			environment["id"] = "environment-id"
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/environments/environment-id":
+			return http.StatusOK, environment
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id":         "bucket-key",
+		"name":              "test-environment",
+		"script":            "var a = 1;",
+		"script_library":    []interface{}{"library-id"},
+		"preserve_cookies":  true,
+		"initial_variables": map[string]interface{}{"var1": "value1"},
+		"integrations":      []interface{}{"integration-id"},
+		"regions":           []interface{}{"us1", "eu1"},
+		"remote_agents": []interface{}{
+			map[string]interface{}{"name": "agent", "uuid": "agent-id"},
+		},
+		"retry_on_failure": true,
+		"verify_ssl":       true,
+		"webhooks":         []interface{}{"https://example.com"},
+		"emails": []interface{}{
+			map[string]interface{}{
+				"notify_all": true,
+				"notify_on":  "all",
+				"recipients": []interface{}{
+					map[string]interface{}{"name": "bob", "id": "person-id", "email": "bob@example.com"},
+				},
+			},
+		},
+	}
+
+	state := testResourceApply(t, resourceRunscopeEnvironment(), nil, raw, client)
+
+	encoded, err := json.Marshal(environment)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	edits := map[string]func(e map[string]interface{}){
+		"":                  func(e map[string]interface{}) {},
+		"name":              func(e map[string]interface{}) { e["name"] = "renamed" },
+		"script":            func(e map[string]interface{}) { e["script"] = "var a = 2;" },
+		"script_library":    func(e map[string]interface{}) { e["script_library"] = []interface{}{} },
+		"preserve_cookies":  func(e map[string]interface{}) { e["preserve_cookies"] = false },
+		"initial_variables": func(e map[string]interface{}) { e["initial_variables"] = map[string]interface{}{"var1": "edited"} },
+		"integrations": func(e map[string]interface{}) {
+			e["integrations"] = []interface{}{
+				map[string]interface{}{"id": "other-id", "integration_type": "slack", "description": "Slack"},
+			}
+		},
+		"regions":          func(e map[string]interface{}) { e["regions"] = []interface{}{"us1"} },
+		"remote_agents":    func(e map[string]interface{}) { delete(e, "remote_agents") },
+		"retry_on_failure": func(e map[string]interface{}) { e["retry_on_failure"] = false },
+		"verify_ssl":       func(e map[string]interface{}) { e["verify_ssl"] = false },
+		"webhooks":         func(e map[string]interface{}) { e["webhooks"] = []interface{}{} },
+		"emails": func(e map[string]interface{}) {
+			e["emails"].(map[string]interface{})["notify_on"] = "failures"
+		},
+	}
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for field, edit := range edits {
+		environment = map[string]interface{}{}
+		if err := json.Unmarshal(encoded, &environment); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		edit(environment)
+
+		r := resourceRunscopeEnvironment()
+		d := r.Data(state)
+		if err := r.Read(d, client); err != nil {
+			t.Fatalf("%s: err: %s", field, err)
+		}
+
+		diff, err := r.Diff(d.State(), terraform.NewResourceConfig(c), client)
+		if err != nil {
+			t.Fatalf("%s: err: %s", field, err)
+		}
+
+		if field == "" {
+			if !diff.Empty() {
+				t.Fatalf("Expected no diff without remote changes, got %#v", diff.Attributes)
+			}
+			continue
+		}
+
+		changed := false
+		if diff != nil {
+			for key := range diff.Attributes {
+				if strings.HasPrefix(key, field) {
+					changed = true
+				}
+			}
+		}
+
+		if !changed {
+			t.Errorf("Expected a remote change to %s to show in the plan", field)
+		}
+	}
+}
+
+func TestValidateEmails(t *testing.T) {
+	recipients := func(items ...map[string]interface{}) *schema.Set {
+		set := schema.NewSet(recipientsHash, nil)
+		for _, item := range items {
+			set.Add(item)
+		}
+		return set
+	}
+	bob := map[string]interface{}{"name": "", "id": "", "email": "bob@example.com"}
+
+	cases := []struct {
+		name  string
+		email map[string]interface{}
+		err   string
+	}{
+		{
+			name:  "threshold",
+			email: map[string]interface{}{"notify_on": "threshold", "notify_threshold": 3, "recipients": recipients(bob)},
+		},
+		{
+			name:  "no threshold",
+			email: map[string]interface{}{"notify_on": "all", "notify_threshold": 0, "recipients": recipients(bob)},
+		},
+		{
+			name:  "threshold not set",
+			email: map[string]interface{}{"notify_on": "threshold", "notify_threshold": 0, "recipients": recipients(bob)},
+			err:   "notify_threshold must be at least 1",
+		},
+		{
+			// Ignored rather than rejected, older configs always set it.
+			name:  "threshold with other notify_on",
+			email: map[string]interface{}{"notify_on": "failures", "notify_threshold": 2, "recipients": recipients(bob)},
+		},
+		{
+			name: "recipient without id or email",
+			email: map[string]interface{}{"notify_on": "all", "notify_threshold": 0, "recipients": recipients(
+				map[string]interface{}{"name": "bob", "id": "", "email": ""},
+			)},
+			err: "recipients must have an id or an email",
+		},
+	}
+
+	for _, tc := range cases {
+		err := validateEmails([]interface{}{tc.email})
+		if tc.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.err, err)
+		}
+	}
+
+	if err := validateEmails([]interface{}{}); err != nil {
+		t.Errorf("Unexpected error without email settings: %s", err)
+	}
+}
+
+func TestResourceEnvironmentCreate_recipientByEmail(t *testing.T) {
+	var environment map[string]interface{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key":
+			return http.StatusOK, map[string]interface{}{"key": "bucket-key", "team": map[string]interface{}{"id": "team-id"}}
+		case req.Method == "GET" && req.Path == "/teams/team-id/people":
+			return http.StatusOK, []interface{}{
+				map[string]interface{}{"id": "alice-id", "name": "Alice", "email": "alice@example.com"},
+				map[string]interface{}{"id": "bob-id", "name": "Bob", "email": "Bob@example.com"},
+			}
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/environments":
+			environment = map[string]interface{}{}
+			for k, v := range req.Body {
+				environment[k] = v
+			}
+			environment["id"] = "environment-id"
+			// Runscope returns the recipient as stored on the team.
+			environment["emails"] = map[string]interface{}{
+				"notify_all": false,
+				"notify_on":  "failures",
+				"recipients": []interface{}{
+					map[string]interface{}{"id": "bob-id", "name": "Bob", "email": "Bob@example.com"},
+				},
+			}
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/environments/environment-id":
+			return http.StatusOK, environment
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"name":      "shared",
+		"emails": []interface{}{
+			map[string]interface{}{
+				"notify_all": false,
+				"notify_on":  "failures",
+				"recipients": []interface{}{
+					map[string]interface{}{"email": "bob@example.com"},
+				},
+			},
+		},
+	}
+
+	state := testResourceApply(t, resourceRunscopeEnvironment(), nil, raw, client)
+
+	var sent map[string]interface{}
+	for _, req := range *requests {
+		if req.Method == "POST" {
+			sent = req.Body["emails"].(map[string]interface{})
+		}
+	}
+
+	if _, ok := sent["notify_threshold"]; ok {
+		t.Fatalf("Expected notify_threshold not to be sent, got %#v", sent)
+	}
+
+	expected := []interface{}{map[string]interface{}{"id": "bob-id", "email": "bob@example.com"}}
+	if !reflect.DeepEqual(sent["recipients"], expected) {
+		t.Fatalf("Expected recipient to be resolved to %#v, got %#v", expected, sent["recipients"])
+	}
+
+	recipient := fmt.Sprintf("emails.0.recipients.%d", recipientsHash(map[string]interface{}{"email": "bob@example.com"}))
+	if state.Attributes[recipient+".id"] != "bob-id" || state.Attributes[recipient+".name"] != "Bob" {
+		t.Fatalf("Unexpected recipient in state %#v", state.Attributes)
+	}
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := resourceRunscopeEnvironment().Diff(state, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !diff.Empty() {
+		t.Fatalf("Expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestResourceEnvironmentUpdate_removeClientCertificate(t *testing.T) {
+	environment := map[string]interface{}{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/environments":
+			environment = map[string]interface{}{"id": "environment-id"}
+			for k, v := range req.Body {
+				environment[k] = v
+			}
+			return http.StatusOK, environment
+		case req.Method == "PUT" && req.Path == "/buckets/bucket-key/environments/environment-id":
+			for k, v := range req.Body {
+				environment[k] = v
+			}
+			if environment["client_certificate"] == "" {
+				delete(environment, "client_certificate")
+			}
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/environments/environment-id":
+			return http.StatusOK, environment
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	cert, key := testClientCertificate(t)
+	raw := map[string]interface{}{
+		"bucket_id":          "bucket-key",
+		"name":               "shared",
+		"client_certificate": cert + key,
+	}
+	state := testResourceApply(t, resourceRunscopeEnvironment(), nil, raw, client)
+
+	delete(raw, "client_certificate")
+	state = testResourceApply(t, resourceRunscopeEnvironment(), state, raw, client)
+
+	var update *testAPIRequest
+	for _, req := range *requests {
+		if req.Method == "PUT" {
+			update = req
+		}
+	}
+
+	if update == nil {
+		t.Fatalf("Expected the environment to be updated")
+	}
+
+	if certificate, ok := update.Body["client_certificate"]; !ok || certificate != "" {
+		t.Fatalf("Expected an empty client_certificate to be sent, got %#v", update.Body["client_certificate"])
+	}
+
+	if _, ok := environment["client_certificate"]; ok {
+		t.Fatalf("Expected the client certificate to be removed")
+	}
+
+	if state.Attributes["client_certificate"] != "" {
+		t.Fatalf("Expected client_certificate to be removed from state")
+	}
+}
+
+func TestResourceEnvironmentUpdate_removeParentEnvironment(t *testing.T) {
+	environment := map[string]interface{}{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/tests/test-id/environments":
+			environment = map[string]interface{}{"id": "environment-id"}
+			for k, v := range req.Body {
+				environment[k] = v
+			}
+			return http.StatusOK, environment
+		case req.Method == "PUT" && req.Path == "/buckets/bucket-key/tests/test-id/environments/environment-id":
+			for k, v := range req.Body {
+				environment[k] = v
+			}
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/tests/test-id/environments/environment-id":
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/environments/shared-id":
+			return http.StatusOK, map[string]interface{}{
+				"id":                "shared-id",
+				"initial_variables": map[string]interface{}{"base_url": "https://example.com"},
+			}
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id":             "bucket-key",
+		"test_id":               "test-id",
+		"name":                  "test environment",
+		"parent_environment_id": "shared-id",
+	}
+	state := testResourceApply(t, resourceRunscopeEnvironment(), nil, raw, client)
+
+	delete(raw, "parent_environment_id")
+	state = testResourceApply(t, resourceRunscopeEnvironment(), state, raw, client)
+
+	var update *testAPIRequest
+	for _, req := range *requests {
+		if req.Method == "PUT" {
+			update = req
+		}
+	}
+
+	if update == nil {
+		t.Fatalf("Expected the environment to be updated")
+	}
+
+	if parent, ok := update.Body["parent_environment_id"]; !ok || parent != "" {
+		t.Fatalf("Expected an empty parent_environment_id to be sent, got %#v", update.Body["parent_environment_id"])
+	}
+
+	if state.Attributes["parent_environment_id"] != "" || state.Attributes["effective_variables.%"] != "0" {
+		t.Fatalf("Expected the parent environment to be removed from state %#v", state.Attributes)
+	}
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := resourceRunscopeEnvironment().Diff(state, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !diff.Empty() {
+		t.Fatalf("Expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestResourceEnvironmentCreate_recipientByID(t *testing.T) {
+	var environment map[string]interface{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/environments":
+			environment = map[string]interface{}{}
+			for k, v := range req.Body {
+				environment[k] = v
+			}
+			environment["id"] = "environment-id"
+			environment["emails"] = map[string]interface{}{
+				"notify_all": false,
+				"notify_on":  "failures",
+				"recipients": []interface{}{
+					map[string]interface{}{"id": "bob-id", "name": "Bob", "email": "bob@example.com"},
+				},
+			}
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/environments/environment-id":
+			return http.StatusOK, environment
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"name":      "shared",
+		"emails": []interface{}{
+			map[string]interface{}{
+				"notify_all": false,
+				"notify_on":  "failures",
+				"recipients": []interface{}{
+					map[string]interface{}{"id": "bob-id"},
+				},
+			},
+		},
+	}
+
+	state := testResourceApply(t, resourceRunscopeEnvironment(), nil, raw, client)
+
+	// Recipients given by id don't need to be looked up.
+	if len(*requests) != 2 {
+		t.Fatalf("Expected only the environment to be created and read, got %d requests", len(*requests))
+	}
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := resourceRunscopeEnvironment().Diff(state, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !diff.Empty() {
+		t.Fatalf("Expected no diff, got %#v", diff.Attributes)
+	}
+
+	raw["emails"].([]interface{})[0].(map[string]interface{})["recipients"] = []interface{}{
+		map[string]interface{}{"id": "alice-id"},
+	}
+	c, err = tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err = resourceRunscopeEnvironment().Diff(state, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if diff.Empty() {
+		t.Fatalf("Expected a diff changing the recipient")
+	}
+}
+
+func TestResourceEnvironmentCreate_legacyNotifyThreshold(t *testing.T) {
+	var environment map[string]interface{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/environments":
+			environment = map[string]interface{}{"id": "environment-id"}
+			for k, v := range req.Body {
+				environment[k] = v
+			}
+			return http.StatusOK, environment
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/environments/environment-id":
+			return http.StatusOK, environment
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	// notify_threshold used to be required, whatever notify_on was set to.
+	raw := map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"name":      "shared",
+		"emails": []interface{}{
+			map[string]interface{}{
+				"notify_all":       true,
+				"notify_on":        "all",
+				"notify_threshold": 1,
+				"recipients": []interface{}{
+					map[string]interface{}{"id": "bob-id", "name": "bob", "email": "bob@example.com"},
+				},
+			},
+		},
+	}
+
+	state := testResourceApply(t, resourceRunscopeEnvironment(), nil, raw, client)
+
+	emails := (*requests)[0].Body["emails"].(map[string]interface{})
+	if _, ok := emails["notify_threshold"]; ok {
+		t.Fatalf("Expected notify_threshold not to be sent, got %#v", emails)
+	}
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := resourceRunscopeEnvironment().Diff(state, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !diff.Empty() {
+		t.Fatalf("Expected no diff, got %#v", diff.Attributes)
+	}
+}
@@ -7,13 +7,18 @@ import (
 
 	runscope "github.com/ewilde/go-runscope"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 )
 
 func resourceRunscopeSchedule() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceScheduleCreate,
 		Read:   resourceScheduleRead,
+		Update: resourceScheduleUpdate,
 		Delete: resourceScheduleDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceScheduleImport,
+		},
 
 		Schema: map[string]*schema.Schema{
 			"bucket_id": {
@@ -29,18 +34,21 @@ func resourceRunscopeSchedule() *schema.Resource {
 			"environment_id": {
 				Type:     schema.TypeString,
 				Required: true,
-				ForceNew: true,
+				ForceNew: false,
 			},
 			"interval": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: false,
+				ValidateFunc: validation.StringInSlice([]string{
+					"1m", "5m", "15m", "30m", "1h", "6h", "1d",
+				}, false),
 			},
 			"note": {
 				Type:     schema.TypeString,
 				Required: false,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: false,
 			},
 		},
 	}
@@ -93,6 +101,108 @@ func resourceScheduleRead(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
+func resourceScheduleUpdate(d *schema.ResourceData, meta interface{}) error {
+	d.Partial(false)
+	scheduleFromResource, bucketID, testID, err := createScheduleFromResourceData(d)
+	if err != nil {
+		return fmt.Errorf("Error updating schedule: %s", err)
+	}
+
+	if d.HasChange("environment_id") ||
+		d.HasChange("interval") ||
+		d.HasChange("note") {
+		client := meta.(*runscope.Client)
+		if d.HasChange("note") && scheduleFromResource.Note == "" {
+			err = clearScheduleNote(client, scheduleFromResource, bucketID, testID)
+		} else {
+			_, err = client.UpdateSchedule(scheduleFromResource, bucketID, testID)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Error updating schedule: %s", err)
+		}
+	}
+
+	return resourceScheduleRead(d, meta)
+}
+
+func resourceScheduleImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.Split(d.Id(), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("Unexpected format of ID (%s), expected bucket_key/test_id/schedule_id "+
+			"or bucket_key/test_id/environment_id", d.Id())
+	}
+
+	bucketID, testID := parts[0], parts[1]
+	d.Set("bucket_id", bucketID)
+	d.Set("test_id", testID)
+	d.SetId(parts[2])
+
+	err := resourceScheduleRead(d, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Id() == "" {
+		// The last part of the ID isn't a schedule, see if it names
+		// the environment a schedule runs against instead.
+		scheduleID, err := findScheduleIDByEnvironment(meta.(*runscope.Client), bucketID, testID, parts[2])
+		if err != nil {
+			return nil, err
+		}
+
+		d.SetId(scheduleID)
+		err = resourceScheduleRead(d, meta)
+		if err != nil {
+			return nil, err
+		}
+
+		if d.Id() == "" {
+			return nil, fmt.Errorf("Couldn't find schedule: %s", scheduleID)
+		}
+	}
+
+	results := []*schema.ResourceData{d}
+
+	return results, nil
+}
+
+func findScheduleIDByEnvironment(client *runscope.Client, bucketID string, testID string, environmentID string) (string, error) {
+	schedules, err := client.ListSchedules(bucketID, testID)
+	if err != nil {
+		return "", fmt.Errorf("Couldn't list schedules for test %s: %s", testID, err)
+	}
+
+	var found []string
+	for _, schedule := range schedules {
+		if schedule.EnvironmentID == environmentID {
+			found = append(found, schedule.ID)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("Couldn't find schedule with id or environment id: %s", environmentID)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("Found %d schedules for environment %s, import using the schedule id instead",
+			len(found), environmentID)
+	}
+}
+
+// clearScheduleNote updates a schedule with an explicitly empty note, which
+// runscope.Schedule omits.
+func clearScheduleNote(client *runscope.Client, schedule *runscope.Schedule, bucketID string, testID string) error {
+	_, err := apiRequest(client, "PUT", fmt.Sprintf("/buckets/%s/tests/%s/schedules/%s", bucketID, testID, schedule.ID),
+		map[string]interface{}{
+			"environment_id": schedule.EnvironmentID,
+			"interval":       schedule.Interval,
+			"note":           "",
+		})
+	return err
+}
+
 func resourceScheduleDelete(d *schema.ResourceData, meta interface{}) error {
 	client := meta.(*runscope.Client)
 
@@ -2,6 +2,7 @@ package runscope
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"testing"
 
@@ -30,6 +31,34 @@ func TestAccSchedule_basic(t *testing.T) {
 	})
 }
 
+func TestAccSchedule_update(t *testing.T) {
+	var scheduleID string
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckScheduleDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testRunscopeScheduleConfigA, teamID),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckScheduleExists("runscope_schedule.daily"),
+					testAccCheckScheduleID("runscope_schedule.daily", &scheduleID, false)),
+			},
+			{
+				Config: fmt.Sprintf(testRunscopeScheduleConfigUpdated, teamID),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckScheduleExists("runscope_schedule.daily"),
+					testAccCheckScheduleID("runscope_schedule.daily", &scheduleID, true),
+					resource.TestCheckResourceAttr(
+						"runscope_schedule.daily", "note", "This is a quarter hourly schedule"),
+					resource.TestCheckResourceAttr(
+						"runscope_schedule.daily", "interval", "15m")),
+			},
+		},
+	})
+}
+
 func testAccCheckScheduleDestroy(s *terraform.State) error {
 	client := testAccProvider.Meta().(*runscope.Client)
 
@@ -87,6 +116,24 @@ func testAccCheckScheduleExists(n string) resource.TestCheckFunc {
 	}
 }
 
+// testAccCheckScheduleID records the schedule ID, or when compare is set
+// ensures it matches the one previously recorded.
+func testAccCheckScheduleID(n string, id *string, compare bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		if compare && rs.Primary.ID != *id {
+			return fmt.Errorf("Expected schedule %s to be updated in place, got new schedule %s", *id, rs.Primary.ID)
+		}
+
+		*id = rs.Primary.ID
+		return nil
+	}
+}
+
 const testRunscopeScheduleConfigA = `
 resource "runscope_schedule" "daily" {
   bucket_id      = "${runscope_bucket.bucket.id}"
@@ -117,3 +164,85 @@ resource "runscope_environment" "environment" {
   }
 }
 `
+
+const testRunscopeScheduleConfigUpdated = `
+resource "runscope_schedule" "daily" {
+  bucket_id      = "${runscope_bucket.bucket.id}"
+  test_id        = "${runscope_test.test.id}"
+  interval       = "15m"
+  note           = "This is a quarter hourly schedule"
+  environment_id = "${runscope_environment.environment.id}"
+}
+
+resource "runscope_test" "test" {
+  bucket_id   = "${runscope_bucket.bucket.id}"
+  name        = "runscope test"
+  description = "This is a test test..."
+}
+
+resource "runscope_bucket" "bucket" {
+  name      = "terraform-provider-test"
+  team_uuid = "%s"
+}
+
+resource "runscope_environment" "environment" {
+  bucket_id = "${runscope_bucket.bucket.id}"
+  name      = "test-environment"
+
+  initial_variables = {
+    var1 = "true"
+    var2 = "value2"
+  }
+}
+`
+
+func TestResourceScheduleUpdate_clearNote(t *testing.T) {
+	schedule := map[string]interface{}{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		path := "/buckets/bucket-key/tests/test-id/schedules"
+		switch {
+		case req.Method == "POST" && req.Path == path:
+			schedule = map[string]interface{}{"id": "schedule-id"}
+			for k, v := range req.Body {
+				schedule[k] = v
+			}
+			return http.StatusOK, schedule
+		case req.Method == "PUT" && req.Path == path+"/schedule-id":
+			for k, v := range req.Body {
+				schedule[k] = v
+			}
+			return http.StatusOK, schedule
+		case req.Method == "GET" && req.Path == path+"/schedule-id":
+			return http.StatusOK, schedule
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id":      "bucket-key",
+		"test_id":        "test-id",
+		"environment_id": "environment-id",
+		"interval":       "1h",
+		"note":           "daily",
+	}
+	state := testResourceApply(t, resourceRunscopeSchedule(), nil, raw, client)
+
+	delete(raw, "note")
+	state = testResourceApply(t, resourceRunscopeSchedule(), state, raw, client)
+
+	update := (*requests)[len(*requests)-2]
+	if update.Method != "PUT" {
+		t.Fatalf("Expected the schedule to be updated, got %s %s", update.Method, update.Path)
+	}
+
+	if note, ok := update.Body["note"]; !ok || note != "" {
+		t.Fatalf("Expected an empty note to be sent, got %#v", update.Body)
+	}
+
+	if state.Attributes["note"] != "" {
+		t.Fatalf("Expected note to be cleared in state %#v", state.Attributes)
+	}
+}
@@ -3,149 +3,638 @@ package runscope
 import (
 	"fmt"
 	"log"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	runscope "github.com/ewilde/go-runscope"
+	"github.com/hashicorp/terraform/config/hcl2shim"
+	"github.com/hashicorp/terraform/helper/hashcode"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 )
 
 func resourceRunscopeStep() *schema.Resource {
+	stepSchema := runscopeStepSchema()
+	stepSchema["bucket_id"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Required: true,
+		ForceNew: true,
+	}
+	stepSchema["test_id"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Required: true,
+		ForceNew: true,
+	}
+	stepSchema["step_type"].ForceNew = true
+
 	return &schema.Resource{
 		Create: resourceStepCreate,
 		Read:   resourceStepRead,
 		Update: resourceStepUpdate,
 		Delete: resourceStepDelete,
-		Schema: map[string]*schema.Schema{
-			"bucket_id": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
-			},
-			"test_id": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
-			},
-			"step_type": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+		Importer: &schema.ResourceImporter{
+			State: resourceStepImport,
+		},
+		CustomizeDiff: resourceStepCustomizeDiff,
+		Schema:        stepSchema,
+	}
+}
+
+// runscopeStepSchema returns the attributes describing a single step, shared
+// by the runscope_step resource and the steps of runscope_test_steps.
+func runscopeStepSchema() map[string]*schema.Schema {
+	stepSchema := runscopeChildStepSchema()
+	stepSchema["condition"] = &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"left_value": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"comparison": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice(stepComparisons, false),
+				},
+				"right_value": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"step": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Resource{
+						Schema: runscopeChildStepSchema(),
+					},
+				},
 			},
-			"method": {
-				Type:     schema.TypeString,
-				Optional: true,
-				ForceNew: true,
+		},
+	}
+	stepSchema["ghost_inspector"] = &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"integration_id": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"suite_id": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"test_id": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"start_url": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"environment_overrides": {
+					Type:     schema.TypeMap,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
 			},
-			"url": {
-				Type:     schema.TypeString,
-				Optional: true,
-				ForceNew: false,
+		},
+	}
+	stepSchema["subtest"] = &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"bucket_key": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"test_id": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"environment_id": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"params": {
+					Type:     schema.TypeMap,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
 			},
-			// TODO: rename to "variable" for better UX
-			"variables": {
-				Type: schema.TypeSet,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"name": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-						"property": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-						"source": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
+		},
+	}
+
+	return stepSchema
+}
+
+// runscopeChildStepSchema returns the attributes of the steps that can be
+// nested inside a condition step.
+func runscopeChildStepSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"step_type": {
+			Type:     schema.TypeString,
+			Required: true,
+		},
+		"method": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"url": {
+			Type:     schema.TypeString,
+			Optional: true,
+			ForceNew: false,
+		},
+		// TODO: rename to "variable" for better UX
+		"variables": {
+			Type: schema.TypeSet,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+					"property": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"source": {
+						Type:         schema.TypeString,
+						Required:     true,
+						ValidateFunc: validation.StringInSlice(variableSources, false),
 					},
 				},
-				Optional: true,
 			},
-			// TODO: rename to "assertion" for better UX
-			"assertions": {
-				Type: schema.TypeList,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"source": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-						"property": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-						"comparison": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-						"value": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
+			Optional: true,
+		},
+		// TODO: rename to "assertion" for better UX
+		"assertions": {
+			Type: schema.TypeList,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"source": {
+						Type:         schema.TypeString,
+						Required:     true,
+						ValidateFunc: validation.StringInSlice(assertionSources, false),
+					},
+					"property": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"comparison": {
+						Type:         schema.TypeString,
+						Required:     true,
+						ValidateFunc: validation.StringInSlice(stepComparisons, false),
+					},
+					"value": {
+						Type:     schema.TypeString,
+						Optional: true,
 					},
 				},
-				Optional: true,
 			},
-			// TODO: rename to "header" for better UX
-			"headers": {
-				Type:     schema.TypeSet,
-				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"header": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-						"value": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
+			Optional: true,
+		},
+		// TODO: rename to "header" for better UX
+		"headers": {
+			Type:     schema.TypeSet,
+			Optional: true,
+			Set:      headersHash,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"header": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+					"value": {
+						Type:     schema.TypeString,
+						Required: true,
 					},
 				},
 			},
-			"auth": {
-				Type:     schema.TypeSet,
-				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"username": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-						"auth_type": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-						"password": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
+		},
+		"form": {
+			Type:     schema.TypeSet,
+			Optional: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+					"value": {
+						Type:     schema.TypeString,
+						Optional: true,
 					},
 				},
 			},
-			"body": {
-				Type:     schema.TypeString,
-				Optional: true,
+		},
+		"auth": {
+			Type:     schema.TypeSet,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"auth_type": {
+						Type:         schema.TypeString,
+						Required:     true,
+						ValidateFunc: validation.StringInSlice([]string{"basic", "oauth1", "client_certificate"}, false),
+					},
+					"username": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"password": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+					"signature_method": {
+						Type:         schema.TypeString,
+						Optional:     true,
+						ValidateFunc: validation.StringInSlice([]string{"HMAC-SHA1", "RSA-SHA1", "PLAINTEXT"}, false),
+					},
+					"consumer_key": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"consumer_secret": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+					"access_token": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"token_secret": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+					"certificate": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"private_key": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+				},
 			},
-			"scripts": {
-				Type:     schema.TypeList,
-				Optional: true,
-				Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"body": {
+			Type:      schema.TypeString,
+			Optional:  true,
+			StateFunc: normalizeStepBody,
+		},
+		"scripts": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				DiffSuppressFunc: suppressEquivalentScriptDiffs,
 			},
-			"before_scripts": {
-				Type:     schema.TypeList,
-				Optional: true,
-				Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"before_scripts": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				DiffSuppressFunc: suppressEquivalentScriptDiffs,
 			},
-			"note": {
-				Type:     schema.TypeString,
-				Optional: true,
+		},
+		"note": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"pause": {
+			Type:     schema.TypeList,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"duration": {
+						Type:         schema.TypeInt,
+						Required:     true,
+						ValidateFunc: validation.IntAtLeast(1),
+					},
+				},
 			},
 		},
 	}
 }
 
+// stepComparisons are the comparisons Runscope supports for conditions
+// and assertions. See https://www.runscope.com/docs/api/steps#assertions
+var stepComparisons = []string{
+	"equal", "not_equal", "empty", "not_empty", "contains", "does_not_contain",
+	"is_a_number", "equal_number", "is_less_than", "is_less_than_or_equal",
+	"is_greater_than", "is_greater_than_or_equal", "has_key", "has_value", "is_null",
+}
+
+// assertionSources are the sources Runscope supports for assertions.
+// See https://www.runscope.com/docs/api/steps#assertions
+var assertionSources = []string{
+	"response_status", "response_headers", "response_json", "response_xml",
+	"response_text", "response_time", "response_size",
+}
+
+// variableSources are the sources Runscope can extract variables from.
+// See https://www.runscope.com/docs/api/steps#variables
+var variableSources = []string{
+	"response_json", "response_xml", "response_headers", "response_status", "response_time", "response_text",
+}
+
+// assertionPropertySources are the assertion sources that need a property,
+// i.e. a header name, JSON path or XPath expression.
+var assertionPropertySources = []string{"response_headers", "response_json", "response_xml"}
+
+// numericComparisons compare the source against a number.
+var numericComparisons = []string{
+	"equal_number", "is_less_than", "is_less_than_or_equal", "is_greater_than", "is_greater_than_or_equal",
+}
+
+// valuelessComparisons don't compare the source against a value.
+var valuelessComparisons = []string{"empty", "not_empty", "is_a_number", "is_null"}
+
+// authTypeFields lists the fields of the auth block each auth_type requires,
+// along with any it optionally accepts.
+var authTypeFields = []struct {
+	authType string
+	required []string
+	optional []string
+}{
+	{"basic", []string{"username", "password"}, nil},
+	{"oauth1", []string{"signature_method", "consumer_key", "consumer_secret"}, []string{"access_token", "token_secret"}},
+	{"client_certificate", []string{"certificate", "private_key"}, nil},
+}
+
+// stepTypeBlocks lists the step types that are configured through a block,
+// along with the name of that block.
+var stepTypeBlocks = []struct {
+	stepType string
+	block    string
+}{
+	{"pause", "pause"},
+	{"condition", "condition"},
+	{"subtest", "subtest"},
+	{"ghost-inspector", "ghost_inspector"},
+}
+
+// validateStep ensures a step is configured consistently, checks that need
+// more than one attribute can't be expressed in the schema.
+func validateStep(attributes map[string]interface{}) error {
+	if err := validateStepVariables(attributes); err != nil {
+		return err
+	}
+
+	if err := validateStepAssertions(attributes); err != nil {
+		return err
+	}
+
+	if err := validateStepAuth(attributes); err != nil {
+		return err
+	}
+
+	return validateStepType(attributes)
+}
+
+// validateStepAuth ensures the auth block sets the fields its auth_type
+// requires, and none belonging to other auth types.
+func validateStepAuth(attributes map[string]interface{}) error {
+	auth, ok := attributes["auth"].(*schema.Set)
+	if !ok || auth.Len() == 0 {
+		return nil
+	}
+
+	item, ok := auth.List()[0].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	authType, _ := item["auth_type"].(string)
+	for _, fields := range authTypeFields {
+		if fields.authType != authType {
+			continue
+		}
+
+		for _, field := range fields.required {
+			if value, _ := item[field].(string); value == "" {
+				return fmt.Errorf("auth: %s must be set when auth_type is %q", field, authType)
+			}
+		}
+
+		for field := range runscopeStepSchema()["auth"].Elem.(*schema.Resource).Schema {
+			value, _ := item[field].(string)
+			if field == "auth_type" || value == "" ||
+				contains(fields.required, field) || contains(fields.optional, field) {
+				continue
+			}
+
+			return fmt.Errorf("auth: %s can't be set when auth_type is %q", field, authType)
+		}
+	}
+
+	return nil
+}
+
+// validateStepVariables ensures each variable sets a property that suits
+// its source.
+func validateStepVariables(attributes map[string]interface{}) error {
+	variables, ok := attributes["variables"].(*schema.Set)
+	if !ok {
+		return nil
+	}
+
+	for _, x := range variables.List() {
+		item, ok := x.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		name, _ := item["name"].(string)
+		source, _ := item["source"].(string)
+		property, _ := item["property"].(string)
+		if source == hcl2shim.UnknownVariableValue || property == hcl2shim.UnknownVariableValue {
+			continue
+		}
+
+		if err := validateVariableProperty(source, property); err != nil {
+			return fmt.Errorf("variables: %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+var (
+	jsonPathPattern   = regexp.MustCompile(`^(\[\d+\]|[^.\[\]]+)(\.[^.\[\]]+|\[\d+\])*$`)
+	headerNamePattern = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+)
+
+// validateVariableProperty checks the property of a variable is valid for
+// its source: a JSON path such as data.items[0].id, an XPath expression or
+// a header name. Status and response time variables don't use a property.
+func validateVariableProperty(source string, property string) error {
+	switch source {
+	case "response_json":
+		if !jsonPathPattern.MatchString(property) {
+			return fmt.Errorf("property %q isn't a valid JSON path, e.g. data.items[0].id", property)
+		}
+	case "response_xml":
+		if property == "" || strings.Count(property, "[") != strings.Count(property, "]") ||
+			strings.Count(property, "(") != strings.Count(property, ")") {
+			return fmt.Errorf("property %q isn't a valid XPath expression", property)
+		}
+	case "response_headers":
+		if !headerNamePattern.MatchString(property) {
+			return fmt.Errorf("property %q isn't a valid header name", property)
+		}
+	case "response_status", "response_time":
+		if property != "" {
+			return fmt.Errorf("property can't be set when source is %q", source)
+		}
+	}
+
+	return nil
+}
+
+// validateStepAssertions ensures each assertion sets a property when its
+// source needs one, and a value that suits its comparison. Numbers must be
+// written the way they are read back.
+func validateStepAssertions(attributes map[string]interface{}) error {
+	assertions, _ := attributes["assertions"].([]interface{})
+	for i, x := range assertions {
+		item, ok := x.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		source, _ := item["source"].(string)
+		property, _ := item["property"].(string)
+		comparison, _ := item["comparison"].(string)
+		value, _ := item["value"].(string)
+
+		if source != hcl2shim.UnknownVariableValue && property != hcl2shim.UnknownVariableValue {
+			if contains(assertionPropertySources, source) && property == "" {
+				return fmt.Errorf("assertions.%d: property must be set when source is %q", i, source)
+			}
+
+			if !contains(assertionPropertySources, source) && property != "" {
+				return fmt.Errorf("assertions.%d: property can't be set when source is %q", i, source)
+			}
+		}
+
+		if value == "" || value == hcl2shim.UnknownVariableValue {
+			continue
+		}
+
+		if contains(valuelessComparisons, comparison) {
+			return fmt.Errorf("assertions.%d: value can't be set when comparison is %q", i, comparison)
+		}
+
+		if contains(numericComparisons, comparison) && !isTemplateToken(value) {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("assertions.%d: value must be a number when comparison is %q, got %q",
+					i, comparison, value)
+			}
+
+			// Numbers are read back in this form, anything else would
+			// always show a diff.
+			if canonical := readAssertionValue(number); canonical != value {
+				return fmt.Errorf("assertions.%d: value must be written as %q, got %q", i, canonical, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateStepType ensures a step configures the block its step_type needs,
+// and none of the blocks belonging to other step types.
+func validateStepType(attributes map[string]interface{}) error {
+	if form, ok := attributes["form"].(*schema.Set); ok && form.Len() > 0 {
+		if body, _ := attributes["body"].(string); body != "" {
+			return fmt.Errorf("\"form\" conflicts with \"body\", only one of them can be set")
+		}
+	}
+
+	stepType, _ := attributes["step_type"].(string)
+	if stepType == "" || stepType == hcl2shim.UnknownVariableValue {
+		return nil
+	}
+
+	if form, ok := attributes["form"].(*schema.Set); ok && form.Len() > 0 && stepType != "request" {
+		return fmt.Errorf("\"form\" can only be set when step_type is \"request\", got %q", stepType)
+	}
+
+	for _, stepTypeBlock := range stepTypeBlocks {
+		configured := false
+		if items, ok := attributes[stepTypeBlock.block].([]interface{}); ok && len(items) > 0 {
+			configured = true
+		}
+
+		if configured && stepType != stepTypeBlock.stepType {
+			return fmt.Errorf("%q can only be set when step_type is %q, got %q",
+				stepTypeBlock.block, stepTypeBlock.stepType, stepType)
+		}
+
+		if !configured && stepType == stepTypeBlock.stepType {
+			return fmt.Errorf("%q must be set when step_type is %q", stepTypeBlock.block, stepTypeBlock.stepType)
+		}
+	}
+
+	if items, ok := attributes["ghost_inspector"].([]interface{}); ok && len(items) > 0 && items[0] != nil {
+		item := items[0].(map[string]interface{})
+		suiteID, _ := item["suite_id"].(string)
+		testID, _ := item["test_id"].(string)
+		if (suiteID == "") == (testID == "") {
+			return fmt.Errorf("\"ghost_inspector\" must set exactly one of suite_id or test_id")
+		}
+	}
+
+	if items, ok := attributes["condition"].([]interface{}); ok && len(items) > 0 && items[0] != nil {
+		children, _ := items[0].(map[string]interface{})["step"].([]interface{})
+		for i, x := range children {
+			child, ok := x.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			childStepType, _ := child["step_type"].(string)
+			if childStepType != "request" && childStepType != "pause" &&
+				childStepType != "" && childStepType != hcl2shim.UnknownVariableValue {
+				return fmt.Errorf("condition.0.step.%d: step_type must be \"request\" or \"pause\", got %q",
+					i, childStepType)
+			}
+
+			if err := validateStep(child); err != nil {
+				return fmt.Errorf("condition.0.step.%d: %s", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func resourceStepCustomizeDiff(diff *schema.ResourceDiff, meta interface{}) error {
+	attributes := map[string]interface{}{}
+	for key := range runscopeStepSchema() {
+		attributes[key] = diff.Get(key)
+	}
+
+	return validateStep(attributes)
+}
+
 func resourceStepCreate(d *schema.ResourceData, meta interface{}) error {
 	client := meta.(*runscope.Client)
 
@@ -154,9 +643,13 @@ func resourceStepCreate(d *schema.ResourceData, meta interface{}) error {
 		return err
 	}
 
+	if err := validateStepIntegrations(client, bucketID, step); err != nil {
+		return fmt.Errorf("Failed to create step: %s", err)
+	}
+
 	log.Printf("[DEBUG] step create: %#v", step)
 
-	createdStep, err := client.CreateTestStep(step, bucketID, testID)
+	createdStep, err := createTestStep(client, step, bucketID, testID)
 	if err != nil {
 		return fmt.Errorf("Failed to create step: %s", err)
 	}
@@ -175,7 +668,7 @@ func resourceStepRead(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Failed to read step from resource data: %s", err)
 	}
 
-	step, err := client.ReadTestStep(stepFromResource, bucketID, testID)
+	step, err := readTestStep(client, stepFromResource.ID, bucketID, testID)
 	if err != nil {
 		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "403") {
 			d.SetId("")
@@ -187,24 +680,8 @@ func resourceStepRead(d *schema.ResourceData, meta interface{}) error {
 
 	d.Set("bucket_id", bucketID)
 	d.Set("test_id", testID)
-	d.Set("step_type", step.StepType)
-	d.Set("method", step.Method)
-	d.Set("url", step.URL)
-	d.Set("body", step.Body)
-	d.Set("variables", readVariables(step.Variables))
-	d.Set("assertions", readAssertions(step.Assertions))
-	d.Set("headers", readHeaders(step.Headers))
-	d.Set("scripts", step.Scripts)
-	d.Set("before_scripts", step.BeforeScripts)
-	d.Set("note", step.Note)
-	if step.Auth != nil && len(step.Auth) > 0 {
-		d.Set("auth", []interface{}{
-			map[string]interface{}{
-				"username":  step.Auth["username"],
-				"auth_type": step.Auth["auth_type"],
-				"password":  step.Auth["password"],
-			},
-		})
+	for key, value := range flattenStep(step) {
+		d.Set(key, value)
 	}
 
 	return nil
@@ -217,23 +694,61 @@ func resourceStepUpdate(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Error updating step: %s", err)
 	}
 
-	if d.HasChange("url") ||
-		d.HasChange("variables") ||
-		d.HasChange("assertions") ||
-		d.HasChange("headers") ||
-		d.HasChange("body") ||
-		d.HasChange("note") {
-		client := meta.(*runscope.Client)
-		_, err = client.UpdateTestStep(stepFromResource, bucketID, testID)
-
-		if err != nil {
-			return fmt.Errorf("Error updating step: %s", err)
+	// Every attribute apart from step_type can be changed in place, which
+	// keeps the step in the same position in the test.
+	changed := false
+	for key := range runscopeStepSchema() {
+		if d.HasChange(key) {
+			changed = true
+			break
 		}
 	}
 
+	if !changed {
+		return nil
+	}
+
+	client := meta.(*runscope.Client)
+	if err := validateStepIntegrations(client, bucketID, stepFromResource); err != nil {
+		return fmt.Errorf("Error updating step: %s", err)
+	}
+
+	step, err := updateTestStep(client, stepFromResource, bucketID, testID)
+	if err != nil {
+		return fmt.Errorf("Error updating step: %s", err)
+	}
+
+	for key, value := range flattenStep(step) {
+		d.Set(key, value)
+	}
+
 	return nil
 }
 
+func resourceStepImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.Split(d.Id(), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("Unexpected format of ID (%s), expected bucket_key/test_id/step_id", d.Id())
+	}
+
+	d.Set("bucket_id", parts[0])
+	d.Set("test_id", parts[1])
+	d.SetId(parts[2])
+
+	err := resourceStepRead(d, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Id() == "" {
+		return nil, fmt.Errorf("Couldn't find step: %s", parts[2])
+	}
+
+	results := []*schema.ResourceData{d}
+
+	return results, nil
+}
+
 func resourceStepDelete(d *schema.ResourceData, meta interface{}) error {
 	client := meta.(*runscope.Client)
 
@@ -242,7 +757,7 @@ func resourceStepDelete(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Failed to read step from resource data: %s", err)
 	}
 
-	err = client.DeleteTestStep(stepFromResource, bucketID, testID)
+	err = client.DeleteTestStep(stepFromResource.TestStep, bucketID, testID)
 	if err != nil {
 		return fmt.Errorf("Error deleting step: %s", err)
 	}
@@ -250,26 +765,38 @@ func resourceStepDelete(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
-func createStepFromResourceData(d *schema.ResourceData) (*runscope.TestStep, string, string, error) {
+func createStepFromResourceData(d *schema.ResourceData) (*testStep, string, string, error) {
+
+	attributes := map[string]interface{}{}
+	for key := range runscopeStepSchema() {
+		attributes[key] = d.Get(key)
+	}
 
-	step := runscope.NewTestStep()
+	step := expandStep(attributes)
+	step.ID = d.Id()
 	bucketID := d.Get("bucket_id").(string)
 	testID := d.Get("test_id").(string)
-	step.ID = d.Id()
-	step.StepType = d.Get("step_type").(string)
-	step.Body = d.Get("body").(string)
-	if attr, ok := d.GetOk("method"); ok {
-		step.Method = attr.(string)
+
+	return step, bucketID, testID, nil
+}
+
+// expandStep builds a step from attributes described by runscopeStepSchema.
+func expandStep(attributes map[string]interface{}) *testStep {
+
+	step := newTestStep()
+	step.StepType = attributes["step_type"].(string)
+	step.Body = attributes["body"].(string)
+	if attr, ok := attributes["method"].(string); ok && attr != "" {
+		step.Method = attr
 	}
 
-	if attr, ok := d.GetOk("url"); ok {
-		step.URL = attr.(string)
+	if attr, ok := attributes["url"].(string); ok && attr != "" {
+		step.URL = attr
 	}
 
-	if attr, ok := d.GetOk("variables"); ok {
+	if attr, ok := attributes["variables"].(*schema.Set); ok && attr.Len() > 0 {
 		variables := []*runscope.Variable{}
-		items := attr.(*schema.Set)
-		for _, x := range items.List() {
+		for _, x := range attr.List() {
 			item := x.(map[string]interface{})
 			variable := runscope.Variable{
 				Name:     item["name"].(string),
@@ -282,28 +809,31 @@ func createStepFromResourceData(d *schema.ResourceData) (*runscope.TestStep, str
 		step.Variables = variables
 	}
 
-	if v, _ := d.GetOk("auth"); v != nil {
-		authSet := v.(*schema.Set).List()
+	if attr, ok := attributes["auth"].(*schema.Set); ok {
+		authSet := attr.List()
 		if len(authSet) == 1 {
 			authMap := authSet[0].(map[string]interface{})
 			auth := make(map[string]string)
 			for key, value := range authMap {
-				auth[key] = value.(string)
+				if value.(string) != "" {
+					auth[key] = value.(string)
+				}
 			}
 			step.Auth = auth
 		}
 	}
 
-	if attr, ok := d.GetOk("assertions"); ok {
+	if attr, ok := attributes["assertions"].([]interface{}); ok && len(attr) > 0 {
 		assertions := []*runscope.Assertion{}
-		items := attr.([]interface{})
-		for _, x := range items {
+		for _, x := range attr {
 			item := x.(map[string]interface{})
+			source := item["source"].(string)
+			comparison := item["comparison"].(string)
 			variable := runscope.Assertion{
-				Source:     item["source"].(string),
+				Source:     source,
 				Property:   item["property"].(string),
-				Comparison: item["comparison"].(string),
-				Value:      item["value"].(string),
+				Comparison: comparison,
+				Value:      expandAssertionValue(source, comparison, item["value"].(string)),
 			}
 
 			assertions = append(assertions, &variable)
@@ -312,29 +842,140 @@ func createStepFromResourceData(d *schema.ResourceData) (*runscope.TestStep, str
 		step.Assertions = assertions
 	}
 
-	if attr, ok := d.GetOk("headers"); ok {
-		step.Headers = make(map[string][]string)
-		items := attr.(*schema.Set)
-		for _, x := range items.List() {
+	if attr, ok := attributes["headers"].(*schema.Set); ok && attr.Len() > 0 {
+		step.Headers = expandHeaders(attr.List())
+	}
+
+	if attr, ok := attributes["form"].(*schema.Set); ok && attr.Len() > 0 {
+		step.Form = make(map[string][]string)
+		for _, x := range attr.List() {
 			item := x.(map[string]interface{})
-			header := item["header"].(string)
-			step.Headers[header] = append(step.Headers[header], item["value"].(string))
+			name := item["name"].(string)
+			step.Form[name] = append(step.Form[name], item["value"].(string))
 		}
 	}
 
-	if attr, ok := d.GetOk("scripts"); ok {
-		step.Scripts = expandStringList(attr.([]interface{}))
+	if attr, ok := attributes["scripts"].([]interface{}); ok && len(attr) > 0 {
+		step.Scripts = expandStringList(attr)
 	}
 
-	if attr, ok := d.GetOk("before_scripts"); ok {
-		step.BeforeScripts = expandStringList(attr.([]interface{}))
+	if attr, ok := attributes["before_scripts"].([]interface{}); ok && len(attr) > 0 {
+		step.BeforeScripts = expandStringList(attr)
 	}
 
-	if attr, ok := d.GetOk("note"); ok {
-		step.Note = attr.(string)
+	if attr, ok := attributes["note"].(string); ok && attr != "" {
+		step.Note = attr
 	}
 
-	return step, bucketID, testID, nil
+	if attr, ok := attributes["pause"].([]interface{}); ok && len(attr) > 0 && attr[0] != nil {
+		item := attr[0].(map[string]interface{})
+		step.Args = map[string]interface{}{
+			"duration": item["duration"].(int),
+		}
+	}
+
+	if attr, ok := attributes["condition"].([]interface{}); ok && len(attr) > 0 && attr[0] != nil {
+		item := attr[0].(map[string]interface{})
+		step.Args = map[string]interface{}{
+			"left_value":  item["left_value"].(string),
+			"comparison":  item["comparison"].(string),
+			"right_value": item["right_value"].(string),
+		}
+
+		if children, ok := item["step"].([]interface{}); ok && len(children) > 0 {
+			steps := make([]*testStep, 0, len(children))
+			for _, child := range children {
+				steps = append(steps, expandStep(child.(map[string]interface{})))
+			}
+			step.Args["steps"] = steps
+		}
+	}
+
+	if attr, ok := attributes["subtest"].([]interface{}); ok && len(attr) > 0 && attr[0] != nil {
+		item := attr[0].(map[string]interface{})
+		params := []interface{}{}
+		for name, value := range item["params"].(map[string]interface{}) {
+			params = append(params, map[string]interface{}{
+				"name":  name,
+				"value": value.(string),
+			})
+		}
+		sort.Slice(params, func(i, j int) bool {
+			return params[i].(map[string]interface{})["name"].(string) < params[j].(map[string]interface{})["name"].(string)
+		})
+
+		step.Args = map[string]interface{}{
+			"bucket_key":       item["bucket_key"].(string),
+			"test_uuid":        item["test_id"].(string),
+			"environment_uuid": item["environment_id"].(string),
+			"params":           params,
+		}
+	}
+
+	if attr, ok := attributes["ghost_inspector"].([]interface{}); ok && len(attr) > 0 && attr[0] != nil {
+		item := attr[0].(map[string]interface{})
+		overrides := map[string]string{}
+		for name, value := range item["environment_overrides"].(map[string]interface{}) {
+			overrides[name] = value.(string)
+		}
+
+		step.Args = map[string]interface{}{
+			"integration_id":        item["integration_id"].(string),
+			"suite_id":              item["suite_id"].(string),
+			"test_id":               item["test_id"].(string),
+			"start_url":             item["start_url"].(string),
+			"environment_overrides": overrides,
+		}
+	}
+
+	return step
+}
+
+// flattenStep returns the attributes described by runscopeStepSchema for a step.
+func flattenStep(step *testStep) map[string]interface{} {
+	attributes := map[string]interface{}{
+		"step_type":      step.StepType,
+		"method":         step.Method,
+		"url":            step.URL,
+		"body":           normalizeJSONBody(step.Body),
+		"variables":      readVariables(step.Variables),
+		"assertions":     readAssertions(step.Assertions),
+		"headers":        readHeaders(step.Headers),
+		"form":           readForm(step.Form),
+		"scripts":        step.Scripts,
+		"before_scripts": step.BeforeScripts,
+		"note":           step.Note,
+	}
+
+	switch step.StepType {
+	case "pause":
+		attributes["pause"] = readPauseArgs(step.Args)
+	case "condition":
+		attributes["condition"] = readConditionArgs(step.Args)
+	case "subtest":
+		attributes["subtest"] = readSubtestArgs(step.Args)
+	case "ghost-inspector":
+		attributes["ghost_inspector"] = readGhostInspectorArgs(step.Args)
+	}
+
+	// Always set auth, so auth removed outside of terraform shows up as a
+	// change.
+	attributes["auth"] = readAuth(step.Auth)
+
+	return attributes
+}
+
+func readAuth(auth map[string]string) []interface{} {
+	if len(auth) == 0 || auth["auth_type"] == "" {
+		return []interface{}{}
+	}
+
+	item := map[string]interface{}{}
+	for field := range runscopeStepSchema()["auth"].Elem.(*schema.Resource).Schema {
+		item[field] = auth[field]
+	}
+
+	return []interface{}{item}
 }
 
 func readVariables(variables []*runscope.Variable) []map[string]interface{} {
@@ -361,7 +1002,7 @@ func readAssertions(assertions []*runscope.Assertion) []map[string]interface{} {
 			"source":     assertion.Source,
 			"property":   assertion.Property,
 			"comparison": assertion.Comparison,
-			"value":      assertion.Value,
+			"value":      readAssertionValue(assertion.Value),
 		}
 
 		result = append(result, item)
@@ -370,14 +1011,275 @@ func readAssertions(assertions []*runscope.Assertion) []map[string]interface{} {
 	return result
 }
 
+// expandAssertionValue sends an assertion value with the JSON type its
+// comparison expects, numbers for numeric comparisons, booleans when testing
+// a JSON property for equality with true or false and nothing for
+// comparisons without a value. Variable tokens are always sent as strings.
+func expandAssertionValue(source string, comparison string, value string) interface{} {
+	switch {
+	case contains(valuelessComparisons, comparison):
+		return nil
+	case isTemplateToken(value):
+		return value
+	case contains(numericComparisons, comparison):
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case source == "response_json" && (comparison == "equal" || comparison == "not_equal"):
+		if value == "true" || value == "false" {
+			return value == "true"
+		}
+	}
+
+	return value
+}
+
+// readAssertionValue renders an assertion value read from the api as the
+// string stored in the assertions schema.
+func readAssertionValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// isTemplateToken reports whether a value is a single Runscope {{variable}}.
+func isTemplateToken(value string) bool {
+	return strings.HasPrefix(value, "{{") && strings.HasSuffix(value, "}}")
+}
+
+// expandHeaders groups header values by name. Header names are case
+// insensitive, values for names that only differ in case are sent under the
+// first spelling used.
+func expandHeaders(items []interface{}) map[string][]string {
+	headers := make(map[string][]string)
+	names := make(map[string]string)
+	for _, x := range items {
+		item := x.(map[string]interface{})
+		header := item["header"].(string)
+		if name, ok := names[strings.ToLower(header)]; ok {
+			header = name
+		} else {
+			names[strings.ToLower(header)] = header
+		}
+
+		headers[header] = append(headers[header], item["value"].(string))
+	}
+
+	for _, values := range headers {
+		sort.Strings(values)
+	}
+
+	return headers
+}
+
+// readHeaders returns an item for every value of every header, so headers
+// that are repeated with several values round trip.
 func readHeaders(headers map[string][]string) []map[string]interface{} {
-	result := make([]map[string]interface{}, len(headers))
-	for key, header := range headers {
-		result = append(result, map[string]interface{}{
-			"header": key,
-			"value":  header,
-		})
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]map[string]interface{}, 0, len(headers))
+	for _, name := range names {
+		for _, value := range headers[name] {
+			result = append(result, map[string]interface{}{
+				"header": name,
+				"value":  value,
+			})
+		}
+	}
+
+	return result
+}
+
+// headersHash hashes a header ignoring the case of its name, so
+// header names that only differ in case don't show up as a change.
+func headersHash(v interface{}) int {
+	item := v.(map[string]interface{})
+	header, _ := item["header"].(string)
+	value, _ := item["value"].(string)
+	return hashcode.String(fmt.Sprintf("%s-%s-", strings.ToLower(header), value))
+}
+
+func normalizeStepBody(v interface{}) string {
+	body, _ := v.(string)
+	return normalizeJSONBody(body)
+}
+
+func readForm(form map[string][]string) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(form))
+	for name, values := range form {
+		for _, value := range values {
+			result = append(result, map[string]interface{}{
+				"name":  name,
+				"value": value,
+			})
+		}
+	}
+
+	return result
+}
+
+func readPauseArgs(args map[string]interface{}) []interface{} {
+	if len(args) == 0 {
+		return []interface{}{}
+	}
+
+	duration, _ := args["duration"].(float64)
+	return []interface{}{
+		map[string]interface{}{
+			"duration": int(duration),
+		},
+	}
+}
+
+func readConditionArgs(args map[string]interface{}) []interface{} {
+	if len(args) == 0 {
+		return []interface{}{}
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"left_value":  args["left_value"],
+			"comparison":  args["comparison"],
+			"right_value": args["right_value"],
+			"step":        readChildSteps(args["steps"]),
+		},
+	}
+}
+
+// readChildSteps flattens the steps nested inside a condition step, these
+// come back from the api as plain JSON rather than decoded steps.
+func readChildSteps(steps interface{}) []interface{} {
+	result := []interface{}{}
+	items, ok := steps.([]interface{})
+	if !ok {
+		return result
+	}
+
+	childSchema := runscopeChildStepSchema()
+	for _, item := range items {
+		step, err := decodeTestStep(item)
+		if err != nil {
+			log.Printf("[WARN] Unable to read condition step %#v: %s", item, err)
+			continue
+		}
+
+		attributes := flattenStep(step)
+		for key := range attributes {
+			if _, ok := childSchema[key]; !ok {
+				delete(attributes, key)
+			}
+		}
+
+		result = append(result, attributes)
 	}
 
 	return result
 }
+
+func readSubtestArgs(args map[string]interface{}) []interface{} {
+	if len(args) == 0 {
+		return []interface{}{}
+	}
+
+	params := map[string]interface{}{}
+	if items, ok := args["params"].([]interface{}); ok {
+		for _, x := range items {
+			item, ok := x.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			if name, ok := item["name"].(string); ok {
+				params[name] = item["value"]
+			}
+		}
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"bucket_key":     args["bucket_key"],
+			"test_id":        args["test_uuid"],
+			"environment_id": args["environment_uuid"],
+			"params":         params,
+		},
+	}
+}
+
+func readGhostInspectorArgs(args map[string]interface{}) []interface{} {
+	if len(args) == 0 {
+		return []interface{}{}
+	}
+
+	overrides := map[string]interface{}{}
+	if items, ok := args["environment_overrides"].(map[string]interface{}); ok {
+		overrides = items
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"integration_id":        args["integration_id"],
+			"suite_id":              args["suite_id"],
+			"test_id":               args["test_id"],
+			"start_url":             args["start_url"],
+			"environment_overrides": overrides,
+		},
+	}
+}
+
+// validateStepIntegrations ensures the integrations used by ghost inspector
+// steps are configured for the team the bucket belongs to.
+func validateStepIntegrations(client *runscope.Client, bucketID string, steps ...*testStep) error {
+	integrationIDs := []string{}
+	for _, step := range steps {
+		if step.StepType != "ghost-inspector" {
+			continue
+		}
+
+		if integrationID, ok := step.Args["integration_id"].(string); ok {
+			integrationIDs = append(integrationIDs, integrationID)
+		}
+	}
+
+	if len(integrationIDs) == 0 {
+		return nil
+	}
+
+	bucket, err := client.ReadBucket(bucketID)
+	if err != nil {
+		return fmt.Errorf("Couldn't find bucket: %s", err)
+	}
+
+	integrations, err := client.ListIntegrations(bucket.Team.ID)
+	if err != nil {
+		return fmt.Errorf("Couldn't list integrations for team %s: %s", bucket.Team.ID, err)
+	}
+
+	for _, integrationID := range integrationIDs {
+		found := false
+		for _, integration := range integrations {
+			if integration.ID == integrationID || integration.UUID == integrationID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Unable to locate integration %s for team %s", integrationID, bucket.Team.ID)
+		}
+	}
+
+	return nil
+}
@@ -1,12 +1,19 @@
 package runscope
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	runscope "github.com/ewilde/go-runscope"
+	tfconfig "github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -51,6 +58,236 @@ func TestAccStep_multiple_steps(t *testing.T) {
 	})
 }
 
+func TestAccStep_pause_and_condition(t *testing.T) {
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckStepDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testRunscopeStepConfigPauseAndCondition, teamID),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStepExists("runscope_step.pause"),
+					testAccCheckStepExists("runscope_step.condition"),
+					resource.TestCheckResourceAttr("runscope_step.pause", "pause.0.duration", "5"),
+					resource.TestCheckResourceAttr("runscope_step.condition", "condition.0.left_value", "{{status}}"),
+					resource.TestCheckResourceAttr("runscope_step.condition", "condition.0.comparison", "equal_number"),
+					resource.TestCheckResourceAttr("runscope_step.condition", "condition.0.right_value", "200"),
+					resource.TestCheckResourceAttr("runscope_step.condition", "condition.0.step.#", "1"),
+					resource.TestCheckResourceAttr("runscope_step.condition", "condition.0.step.0.url", "http://example.com"),
+				),
+			},
+		},
+	})
+}
+
+func TestValidateStepType(t *testing.T) {
+	cases := []struct {
+		Attributes map[string]interface{}
+		ExpectErr  bool
+	}{
+		{
+			Attributes: map[string]interface{}{"step_type": "request"},
+		},
+		{
+			Attributes: map[string]interface{}{
+				"step_type": "pause",
+				"pause":     []interface{}{map[string]interface{}{"duration": 5}},
+			},
+		},
+		{
+			Attributes: map[string]interface{}{"step_type": "pause"},
+			ExpectErr:  true,
+		},
+		{
+			Attributes: map[string]interface{}{
+				"step_type": "request",
+				"condition": []interface{}{map[string]interface{}{"left_value": "1"}},
+			},
+			ExpectErr: true,
+		},
+		{
+			Attributes: map[string]interface{}{
+				"step_type": "subtest",
+				"pause":     []interface{}{map[string]interface{}{"duration": 5}},
+				"subtest":   []interface{}{map[string]interface{}{"test_id": "abc"}},
+			},
+			ExpectErr: true,
+		},
+		{
+			Attributes: map[string]interface{}{
+				"step_type": "ghost-inspector",
+				"ghost_inspector": []interface{}{map[string]interface{}{
+					"integration_id": "integration-id",
+					"suite_id":       "suite-id",
+				}},
+			},
+		},
+		{
+			Attributes: map[string]interface{}{
+				"step_type": "ghost-inspector",
+				"ghost_inspector": []interface{}{map[string]interface{}{
+					"integration_id": "integration-id",
+					"suite_id":       "suite-id",
+					"test_id":        "test-id",
+				}},
+			},
+			ExpectErr: true,
+		},
+		{
+			Attributes: map[string]interface{}{"step_type": "ghost-inspector"},
+			ExpectErr:  true,
+		},
+		{
+			Attributes: map[string]interface{}{
+				"step_type": "request",
+				"body":      "a=1",
+				"form":      testStepFormSet(map[string]interface{}{"name": "a", "value": "1"}),
+			},
+			ExpectErr: true,
+		},
+		{
+			Attributes: map[string]interface{}{
+				"step_type": "pause",
+				"pause":     []interface{}{map[string]interface{}{"duration": 5}},
+				"form":      testStepFormSet(map[string]interface{}{"name": "a", "value": "1"}),
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for i, tc := range cases {
+		err := validateStepType(tc.Attributes)
+		if tc.ExpectErr && err == nil {
+			t.Fatalf("%d: expected error for %#v", i, tc.Attributes)
+		}
+		if !tc.ExpectErr && err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+	}
+}
+
+func TestExpandStep_args(t *testing.T) {
+	step := expandStep(map[string]interface{}{
+		"step_type": "subtest",
+		"body":      "",
+		"subtest": []interface{}{
+			map[string]interface{}{
+				"bucket_key":     "bucket-key",
+				"test_id":        "test-id",
+				"environment_id": "environment-id",
+				"params": map[string]interface{}{
+					"b": "2",
+					"a": "1",
+				},
+			},
+		},
+	})
+
+	expected := map[string]interface{}{
+		"bucket_key":       "bucket-key",
+		"test_uuid":        "test-id",
+		"environment_uuid": "environment-id",
+		"params": []interface{}{
+			map[string]interface{}{"name": "a", "value": "1"},
+			map[string]interface{}{"name": "b", "value": "2"},
+		},
+	}
+
+	if !reflect.DeepEqual(step.Args, expected) {
+		t.Fatalf("Got:\n\n%#v\n\nExpected:\n\n%#v\n", step.Args, expected)
+	}
+
+	pause := readPauseArgs(map[string]interface{}{"duration": float64(5)})
+	if pause[0].(map[string]interface{})["duration"] != 5 {
+		t.Fatalf("Expected pause duration %d, actual %v", 5, pause[0])
+	}
+
+	subtest := readSubtestArgs(map[string]interface{}{
+		"test_uuid": "test-id",
+		"params": []interface{}{
+			map[string]interface{}{"name": "a", "value": "1"},
+		},
+	})
+	if params := subtest[0].(map[string]interface{})["params"]; !reflect.DeepEqual(params, map[string]interface{}{"a": "1"}) {
+		t.Fatalf("Unexpected subtest params %#v", params)
+	}
+}
+
+func TestStepConditionChildSteps(t *testing.T) {
+	step := expandStep(map[string]interface{}{
+		"step_type": "condition",
+		"body":      "",
+		"condition": []interface{}{
+			map[string]interface{}{
+				"left_value":  "{{feature_flag}}",
+				"comparison":  "equal",
+				"right_value": "on",
+				"step": []interface{}{
+					map[string]interface{}{
+						"step_type": "request",
+						"method":    "POST",
+						"url":       "https://example.com/payments",
+						"body":      "",
+					},
+					map[string]interface{}{
+						"step_type": "pause",
+						"body":      "",
+						"pause":     []interface{}{map[string]interface{}{"duration": 2}},
+					},
+				},
+			},
+		},
+	})
+
+	// Round trip the step through JSON, as it would be sent to and read
+	// back from the api.
+	encoded, err := json.Marshal(step)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	read := newTestStep()
+	if err := json.Unmarshal(encoded, read); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	condition := flattenStep(read)["condition"].([]interface{})[0].(map[string]interface{})
+	children := condition["step"].([]interface{})
+	if len(children) != 2 {
+		t.Fatalf("Expected %d child steps, actual %d", 2, len(children))
+	}
+
+	request := children[0].(map[string]interface{})
+	if request["url"] != "https://example.com/payments" || request["method"] != "POST" {
+		t.Fatalf("Unexpected request child step %#v", request)
+	}
+
+	if _, ok := request["condition"]; ok {
+		t.Fatalf("Child steps shouldn't include a condition attribute")
+	}
+
+	pause := children[1].(map[string]interface{})["pause"].([]interface{})[0].(map[string]interface{})
+	if pause["duration"] != 2 {
+		t.Fatalf("Expected pause duration %d, actual %v", 2, pause["duration"])
+	}
+
+	err = validateStepType(map[string]interface{}{
+		"step_type": "condition",
+		"condition": []interface{}{
+			map[string]interface{}{
+				"step": []interface{}{
+					map[string]interface{}{"step_type": "subtest"},
+				},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatalf("Expected error for subtest nested in a condition step")
+	}
+}
+
 func testAccCheckStepDestroy(s *terraform.State) error {
 	client := testAccProvider.Meta().(*runscope.Client)
 
@@ -255,7 +492,7 @@ resource "runscope_step" "main_page" {
   	}
   	variables {
   	   name     = "httpContentEncoding"
-  	   source   = "response_header"
+  	   source   = "response_headers"
   	   property = "Content-Encoding"
   	}
   
@@ -342,3 +579,735 @@ resource "runscope_bucket" "bucket" {
   team_uuid = "%s"
 }
 `
+
+const testRunscopeStepConfigPauseAndCondition = `
+resource "runscope_step" "pause" {
+  bucket_id = "${runscope_bucket.bucket.id}"
+  test_id   = "${runscope_test.test.id}"
+  step_type = "pause"
+  pause {
+    duration = 5
+  }
+}
+
+resource "runscope_step" "condition" {
+  bucket_id = "${runscope_bucket.bucket.id}"
+  test_id   = "${runscope_test.test.id}"
+  step_type = "condition"
+  condition {
+    left_value  = "{{status}}"
+    comparison  = "equal_number"
+    right_value = "200"
+
+    step {
+      step_type = "request"
+      method    = "GET"
+      url       = "http://example.com"
+    }
+  }
+  depends_on = ["runscope_step.pause"]
+}
+
+resource "runscope_test" "test" {
+  bucket_id   = "${runscope_bucket.bucket.id}"
+  name        = "runscope test"
+  description = "This is a test test..."
+}
+
+resource "runscope_bucket" "bucket" {
+  name      = "terraform-provider-test"
+  team_uuid = "%s"
+}
+`
+
+func TestValidateStepIntegrations(t *testing.T) {
+	client, _, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key":
+			return http.StatusOK, map[string]interface{}{"key": "bucket-key", "team": map[string]interface{}{"id": "team-id"}}
+		case req.Method == "GET" && req.Path == "/teams/team-id/integrations":
+			return http.StatusOK, []interface{}{
+				map[string]interface{}{"id": "integration-id", "uuid": "integration-uuid", "type": "ghostinspector"},
+			}
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	step := expandStep(map[string]interface{}{
+		"step_type": "ghost-inspector",
+		"body":      "",
+		"ghost_inspector": []interface{}{
+			map[string]interface{}{
+				"integration_id": "integration-uuid",
+				"suite_id":       "suite-id",
+				"test_id":        "",
+				"start_url":      "https://example.com",
+				"environment_overrides": map[string]interface{}{
+					"username": "{{username}}",
+				},
+			},
+		},
+	})
+
+	if err := validateStepIntegrations(client, "bucket-key", step); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ghostInspector := readGhostInspectorArgs(step.Args)[0].(map[string]interface{})
+	if ghostInspector["start_url"] != "https://example.com" {
+		t.Fatalf("Expected start_url %s, actual %v", "https://example.com", ghostInspector["start_url"])
+	}
+
+	step.Args["integration_id"] = "unknown-id"
+	if err := validateStepIntegrations(client, "bucket-key", step); err == nil {
+		t.Fatalf("Expected error for unknown integration")
+	}
+}
+
+func TestStepForm(t *testing.T) {
+	step := expandStep(map[string]interface{}{
+		"step_type": "request",
+		"method":    "POST",
+		"body":      "",
+		"form": testStepFormSet(
+			map[string]interface{}{"name": "tag", "value": "a"},
+			map[string]interface{}{"name": "tag", "value": "b"},
+			map[string]interface{}{"name": "name", "value": "test"},
+		),
+	})
+
+	encoded, err := json.Marshal(step)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var sent map[string]interface{}
+	if err := json.Unmarshal(encoded, &sent); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tags, _ := sent["form"].(map[string]interface{})["tag"].([]interface{})
+	sort.Slice(tags, func(i, j int) bool { return tags[i].(string) < tags[j].(string) })
+	if !reflect.DeepEqual(tags, []interface{}{"a", "b"}) {
+		t.Fatalf("Expected form values %v for tag, actual %v", []interface{}{"a", "b"}, tags)
+	}
+
+	read, err := decodeTestStep(sent)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	form := schema.NewSet(schema.HashResource(resourceRunscopeStep().Schema["form"].Elem.(*schema.Resource)), nil)
+	for _, item := range flattenStep(read)["form"].([]map[string]interface{}) {
+		form.Add(item)
+	}
+
+	if !form.Equal(testStepFormSet(
+		map[string]interface{}{"name": "name", "value": "test"},
+		map[string]interface{}{"name": "tag", "value": "b"},
+		map[string]interface{}{"name": "tag", "value": "a"},
+	)) {
+		t.Fatalf("Unexpected form %#v", form.List())
+	}
+}
+
+func testStepFormSet(items ...map[string]interface{}) *schema.Set {
+	set := schema.NewSet(schema.HashResource(resourceRunscopeStep().Schema["form"].Elem.(*schema.Resource)), nil)
+	for _, item := range items {
+		set.Add(item)
+	}
+
+	return set
+}
+
+func TestResourceStepCreate_jsonBody(t *testing.T) {
+	step := map[string]interface{}{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/tests/test-id/steps":
+			step = map[string]interface{}{}
+			for key, value := range req.Body {
+				step[key] = value
+			}
+			step["id"] = "step-id"
+			// Runscope hands the body back formatted differently.
+			step["body"] = `{"name":"{{name}}","id":{{id}}}`
+			return http.StatusOK, []interface{}{step}
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/tests/test-id/steps/step-id":
+			return http.StatusOK, step
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"test_id":   "test-id",
+		"step_type": "request",
+		"method":    "POST",
+		"url":       "https://example.com",
+		"body":      "{\"id\": {{id}}, \"name\": \"{{name}}\"}",
+	}
+
+	state := testResourceApply(t, resourceRunscopeStep(), nil, raw, client)
+
+	if sent := (*requests)[0].Body["body"]; sent != raw["body"] {
+		t.Fatalf("Expected body %q to be sent, actual %q", raw["body"], sent)
+	}
+
+	expected := "{\n  \"id\": {{id}},\n  \"name\": \"{{name}}\"\n}"
+	if state.Attributes["body"] != expected {
+		t.Fatalf("Expected body %q, actual %q", expected, state.Attributes["body"])
+	}
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := resourceRunscopeStep().Diff(state, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !diff.Empty() {
+		t.Fatalf("Expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestValidateStepAssertions(t *testing.T) {
+	cases := []struct {
+		Assertion map[string]interface{}
+		ExpectErr bool
+	}{
+		{
+			Assertion: map[string]interface{}{"source": "response_status", "comparison": "equal_number", "value": "200"},
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_json", "property": "data.id", "comparison": "equal", "value": "1"},
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_json", "comparison": "equal", "value": "1"},
+			ExpectErr: true,
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_status", "property": "status", "comparison": "equal_number", "value": "200"},
+			ExpectErr: true,
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_time", "comparison": "is_less_than", "value": "fast"},
+			ExpectErr: true,
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_time", "comparison": "is_less_than", "value": "{{max_time}}"},
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_time", "comparison": "is_less_than", "value": "-1.25"},
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_status", "comparison": "equal_number", "value": "200.0"},
+			ExpectErr: true,
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_time", "comparison": "is_less_than", "value": "1e3"},
+			ExpectErr: true,
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_status", "comparison": "equal_number", "value": "007"},
+			ExpectErr: true,
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_json", "property": "id", "comparison": "equal", "value": "007"},
+		},
+		{
+			Assertion: map[string]interface{}{"source": "response_text", "comparison": "not_empty", "value": "x"},
+			ExpectErr: true,
+		},
+	}
+
+	for i, tc := range cases {
+		err := validateStepAssertions(map[string]interface{}{"assertions": []interface{}{tc.Assertion}})
+		if tc.ExpectErr && err == nil {
+			t.Fatalf("%d: expected error for %#v", i, tc.Assertion)
+		}
+		if !tc.ExpectErr && err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+	}
+}
+
+func TestAssertionValue(t *testing.T) {
+	cases := []struct {
+		Source     string
+		Comparison string
+		Value      string
+		Expected   interface{}
+	}{
+		{Source: "response_status", Comparison: "equal_number", Value: "200", Expected: float64(200)},
+		{Source: "response_time", Comparison: "is_less_than", Value: "0.5", Expected: 0.5},
+		{Source: "response_time", Comparison: "is_less_than", Value: "{{limit}}", Expected: "{{limit}}"},
+		{Source: "response_json", Comparison: "equal", Value: "true", Expected: true},
+		{Source: "response_json", Comparison: "not_equal", Value: "false", Expected: false},
+		{Source: "response_headers", Comparison: "equal", Value: "true", Expected: "true"},
+		{Source: "response_text", Comparison: "not_equal", Value: "false", Expected: "false"},
+		{Source: "response_json", Comparison: "equal", Value: "200", Expected: "200"},
+		{Source: "response_json", Comparison: "contains", Value: "true", Expected: "true"},
+		{Source: "response_json", Comparison: "is_null", Value: "", Expected: nil},
+		{Source: "response_text", Comparison: "not_empty", Value: "", Expected: nil},
+	}
+
+	for _, tc := range cases {
+		value := expandAssertionValue(tc.Source, tc.Comparison, tc.Value)
+		if !reflect.DeepEqual(value, tc.Expected) {
+			t.Fatalf("%s %s %q: expected %#v, actual %#v", tc.Source, tc.Comparison, tc.Value, tc.Expected, value)
+		}
+
+		// Round trip the value through JSON, as it would be sent to and read
+		// back from the api.
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		var decoded interface{}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if read := readAssertionValue(decoded); read != tc.Value {
+			t.Fatalf("%s %q: read back %q", tc.Comparison, tc.Value, read)
+		}
+	}
+}
+
+func TestValidateStepAuth(t *testing.T) {
+	authResource := resourceRunscopeStep().Schema["auth"].Elem.(*schema.Resource)
+	cases := []struct {
+		Auth      map[string]interface{}
+		ExpectErr bool
+	}{
+		{
+			Auth: map[string]interface{}{"auth_type": "basic", "username": "bob", "password": "secret"},
+		},
+		{
+			Auth:      map[string]interface{}{"auth_type": "basic", "username": "bob"},
+			ExpectErr: true,
+		},
+		{
+			Auth: map[string]interface{}{
+				"auth_type":        "oauth1",
+				"signature_method": "HMAC-SHA1",
+				"consumer_key":     "key",
+				"consumer_secret":  "secret",
+				"access_token":     "token",
+			},
+		},
+		{
+			Auth: map[string]interface{}{
+				"auth_type":        "oauth1",
+				"signature_method": "HMAC-SHA1",
+				"consumer_key":     "key",
+				"consumer_secret":  "secret",
+				"password":         "secret",
+			},
+			ExpectErr: true,
+		},
+		{
+			Auth:      map[string]interface{}{"auth_type": "client_certificate", "certificate": "cert"},
+			ExpectErr: true,
+		},
+	}
+
+	for i, tc := range cases {
+		auth := schema.NewSet(schema.HashResource(authResource), []interface{}{tc.Auth})
+		err := validateStepAuth(map[string]interface{}{"auth": auth})
+		if tc.ExpectErr && err == nil {
+			t.Fatalf("%d: expected error for %#v", i, tc.Auth)
+		}
+		if !tc.ExpectErr && err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+	}
+}
+
+func TestResourceStepRead_authRemoved(t *testing.T) {
+	step := map[string]interface{}{}
+	client, _, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/tests/test-id/steps":
+			step = req.Body
+			step["id"] = "step-id"
+			return http.StatusOK, []interface{}{step}
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/tests/test-id/steps/step-id":
+			return http.StatusOK, step
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	state := testResourceApply(t, resourceRunscopeStep(), nil, map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"test_id":   "test-id",
+		"step_type": "request",
+		"method":    "GET",
+		"url":       "https://example.com",
+		"auth": []interface{}{
+			map[string]interface{}{"auth_type": "basic", "username": "bob", "password": "secret"},
+		},
+	}, client)
+
+	if state.Attributes["auth.#"] != "1" {
+		t.Fatalf("Expected auth to be set, got %#v", state.Attributes)
+	}
+
+	// Remove the auth outside of terraform.
+	delete(step, "auth")
+
+	r := resourceRunscopeStep()
+	d := r.Data(state)
+	if err := r.Read(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if auth := d.Get("auth").(*schema.Set); auth.Len() != 0 {
+		t.Fatalf("Expected auth removed remotely to be cleared, got %#v", auth.List())
+	}
+}
+
+func TestStepHeaders(t *testing.T) {
+	headers := schema.NewSet(headersHash, []interface{}{
+		map[string]interface{}{"header": "Accept", "value": "text/html"},
+		map[string]interface{}{"header": "accept", "value": "application/json"},
+		map[string]interface{}{"header": "Authorization", "value": "Bearer {{token}}"},
+	})
+
+	expanded := expandHeaders(headers.List())
+	if len(expanded) != 2 {
+		t.Fatalf("Expected %d header names, actual %#v", 2, expanded)
+	}
+
+	var accept []string
+	for name, values := range expanded {
+		if strings.EqualFold(name, "accept") {
+			accept = values
+		}
+	}
+
+	if !reflect.DeepEqual(accept, []string{"application/json", "text/html"}) {
+		t.Fatalf("Unexpected accept values %#v", accept)
+	}
+
+	read := readHeaders(expanded)
+	if len(read) != 3 {
+		t.Fatalf("Expected %d headers, actual %#v", 3, read)
+	}
+
+	for _, item := range read {
+		if _, ok := item["value"].(string); !ok {
+			t.Fatalf("Expected header value to be a string, got %#v", item)
+		}
+	}
+
+	readSet := schema.NewSet(headersHash, nil)
+	for _, item := range read {
+		readSet.Add(item)
+	}
+
+	// Header names only differing in case hash the same, so there is no diff.
+	if readSet.Difference(headers).Len() != 0 || headers.Difference(readSet).Len() != 0 {
+		t.Fatalf("Expected headers %#v, actual %#v", headers.List(), readSet.List())
+	}
+}
+
+func TestStepRequestRoundTrip(t *testing.T) {
+	r := resourceRunscopeStep()
+	attributes := map[string]interface{}{
+		"step_type": "request",
+		"method":    "POST",
+		"url":       "https://example.com/{{id}}",
+		"body":      "{\n  \"a\": 1\n}",
+		"note":      "create a thing",
+		"variables": schema.NewSet(schema.HashResource(r.Schema["variables"].Elem.(*schema.Resource)), []interface{}{
+			map[string]interface{}{"name": "id", "source": "response_json", "property": "data.id"},
+		}),
+		"assertions": []interface{}{
+			map[string]interface{}{"source": "response_status", "property": "", "comparison": "equal_number", "value": "200"},
+		},
+		"headers": schema.NewSet(headersHash, []interface{}{
+			map[string]interface{}{"header": "X-Tag", "value": "a"},
+			map[string]interface{}{"header": "X-Tag", "value": "b"},
+		}),
+		"scripts":        []interface{}{"log(1);"},
+		"before_scripts": []interface{}{"log(2);"},
+	}
+
+	step := expandStep(attributes)
+	encoded, err := json.Marshal(step)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	read, err := decodeTestStep(json.RawMessage(encoded))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	flattened := flattenStep(read)
+	for _, key := range []string{"step_type", "method", "url", "body", "note"} {
+		if flattened[key] != attributes[key] {
+			t.Fatalf("Expected %s %#v, actual %#v", key, attributes[key], flattened[key])
+		}
+	}
+
+	if variables := flattened["variables"].([]map[string]interface{}); len(variables) != 1 || variables[0]["property"] != "data.id" {
+		t.Fatalf("Unexpected variables %#v", variables)
+	}
+
+	if assertions := flattened["assertions"].([]map[string]interface{}); len(assertions) != 1 || assertions[0]["value"] != "200" {
+		t.Fatalf("Unexpected assertions %#v", assertions)
+	}
+
+	if headers := flattened["headers"].([]map[string]interface{}); len(headers) != 2 {
+		t.Fatalf("Unexpected headers %#v", headers)
+	}
+
+	if !reflect.DeepEqual(flattened["scripts"], []string{"log(1);"}) || !reflect.DeepEqual(flattened["before_scripts"], []string{"log(2);"}) {
+		t.Fatalf("Unexpected scripts %#v %#v", flattened["scripts"], flattened["before_scripts"])
+	}
+
+	if auth := flattened["auth"].([]interface{}); len(auth) != 0 {
+		t.Fatalf("Expected no auth, actual %#v", auth)
+	}
+}
+
+func TestResourceStepUpdate_inPlace(t *testing.T) {
+	step := map[string]interface{}{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/tests/test-id/steps":
+			step = req.Body
+			step["id"] = "step-id"
+			return http.StatusOK, []interface{}{step}
+		case req.Method == "PUT" && req.Path == "/buckets/bucket-key/tests/test-id/steps/step-id":
+			step = req.Body
+			step["id"] = "step-id"
+			return http.StatusOK, step
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/tests/test-id/steps/step-id":
+			return http.StatusOK, step
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"test_id":   "test-id",
+		"step_type": "request",
+		"method":    "GET",
+		"url":       "https://example.com",
+		"scripts":   []interface{}{"log(1);"},
+	}
+
+	state := testResourceApply(t, resourceRunscopeStep(), nil, raw, client)
+
+	raw["method"] = "POST"
+	raw["scripts"] = []interface{}{"log(2);"}
+	raw["before_scripts"] = []interface{}{"log(3);"}
+	raw["auth"] = []interface{}{
+		map[string]interface{}{"auth_type": "basic", "username": "bob", "password": "secret"},
+	}
+
+	*requests = nil
+	state = testResourceApply(t, resourceRunscopeStep(), state, raw, client)
+
+	if len(*requests) != 1 || (*requests)[0].Method != "PUT" {
+		t.Fatalf("Expected the step to be updated in place, got %#v", *requests)
+	}
+
+	if state.ID != "step-id" {
+		t.Fatalf("Expected step id %s, actual %s", "step-id", state.ID)
+	}
+
+	for key, expected := range map[string]string{
+		"method":           "POST",
+		"scripts.0":        "log(2);",
+		"before_scripts.0": "log(3);",
+		"auth.#":           "1",
+	} {
+		if state.Attributes[key] != expected {
+			t.Fatalf("Expected %s %q, actual %q", key, expected, state.Attributes[key])
+		}
+	}
+}
+
+func TestResourceStepUpdate_removeFields(t *testing.T) {
+	step := map[string]interface{}{}
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/tests/test-id/steps":
+			step = map[string]interface{}{"id": "step-id"}
+			for k, v := range req.Body {
+				step[k] = v
+			}
+			return http.StatusOK, []interface{}{step}
+		case req.Method == "PUT" && req.Path == "/buckets/bucket-key/tests/test-id/steps/step-id":
+			// Fields missing from the request are left unchanged.
+			for k, v := range req.Body {
+				step[k] = v
+			}
+			return http.StatusOK, step
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/tests/test-id/steps/step-id":
+			return http.StatusOK, step
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id":      "bucket-key",
+		"test_id":        "test-id",
+		"step_type":      "request",
+		"method":         "POST",
+		"url":            "https://example.com",
+		"note":           "a note",
+		"body":           "{\"id\": 1}",
+		"scripts":        []interface{}{"log(1);"},
+		"before_scripts": []interface{}{"log(2);"},
+		"auth": []interface{}{
+			map[string]interface{}{"auth_type": "basic", "username": "bob", "password": "secret"},
+		},
+		"headers": []interface{}{
+			map[string]interface{}{"header": "Accept", "value": "application/json"},
+		},
+		"assertions": []interface{}{
+			map[string]interface{}{"source": "response_status", "comparison": "equal_number", "value": "200"},
+		},
+		"variables": []interface{}{
+			map[string]interface{}{"name": "status", "source": "response_status"},
+		},
+	}
+
+	state := testResourceApply(t, resourceRunscopeStep(), nil, raw, client)
+
+	raw = map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"test_id":   "test-id",
+		"step_type": "request",
+		"method":    "POST",
+		"url":       "https://example.com",
+	}
+
+	*requests = nil
+	state = testResourceApply(t, resourceRunscopeStep(), state, raw, client)
+
+	if len(*requests) != 1 || (*requests)[0].Method != "PUT" {
+		t.Fatalf("Expected the step to be updated in place, got %#v", *requests)
+	}
+
+	for _, field := range []string{"note", "body", "scripts", "before_scripts", "auth", "headers", "assertions", "variables"} {
+		value, ok := (*requests)[0].Body[field]
+		if !ok {
+			t.Errorf("Expected an empty %s to be sent", field)
+		}
+
+		if !reflect.ValueOf(value).IsValid() || reflect.ValueOf(value).Len() != 0 {
+			t.Errorf("Expected an empty %s to be sent, got %#v", field, value)
+		}
+	}
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := resourceRunscopeStep().Diff(state, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !diff.Empty() {
+		t.Fatalf("Expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestResourceStepDiff_scriptWhitespace(t *testing.T) {
+	state := &terraform.InstanceState{
+		ID: "step-id",
+		Attributes: map[string]string{
+			"id":               "step-id",
+			"bucket_id":        "bucket-key",
+			"test_id":          "test-id",
+			"step_type":        "request",
+			"method":           "GET",
+			"url":              "https://example.com",
+			"scripts.#":        "1",
+			"scripts.0":        "log(1);\nlog(2);",
+			"before_scripts.#": "1",
+			"before_scripts.0": "log(3);",
+		},
+	}
+
+	c, err := tfconfig.NewRawConfig(map[string]interface{}{
+		"bucket_id":      "bucket-key",
+		"test_id":        "test-id",
+		"step_type":      "request",
+		"method":         "GET",
+		"url":            "https://example.com",
+		"scripts":        []interface{}{"log(1);  \r\nlog(2);\n"},
+		"before_scripts": []interface{}{"log(3);\n\n"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := resourceRunscopeStep().Diff(state, terraform.NewResourceConfig(c), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !diff.Empty() {
+		t.Fatalf("Expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func TestValidateVariableProperty(t *testing.T) {
+	cases := []struct {
+		Source    string
+		Property  string
+		ExpectErr bool
+	}{
+		{Source: "response_json", Property: "data.items[0].id"},
+		{Source: "response_json", Property: "[0].id"},
+		{Source: "response_json", Property: "data..id", ExpectErr: true},
+		{Source: "response_json", Property: "data.items[0", ExpectErr: true},
+		{Source: "response_json", Property: "", ExpectErr: true},
+		{Source: "response_xml", Property: "/order/items/item[1]/@id"},
+		{Source: "response_xml", Property: "/order/items/item[1", ExpectErr: true},
+		{Source: "response_headers", Property: "Content-Encoding"},
+		{Source: "response_headers", Property: "Content Encoding", ExpectErr: true},
+		{Source: "response_status"},
+		{Source: "response_time", Property: "ms", ExpectErr: true},
+		{Source: "response_text"},
+	}
+
+	for _, tc := range cases {
+		err := validateVariableProperty(tc.Source, tc.Property)
+		if tc.ExpectErr && err == nil {
+			t.Fatalf("%s %q: expected error", tc.Source, tc.Property)
+		}
+		if !tc.ExpectErr && err != nil {
+			t.Fatalf("%s %q: unexpected error: %s", tc.Source, tc.Property, err)
+		}
+	}
+}
@@ -18,6 +18,9 @@ func resourceRunscopeTest() *schema.Resource {
 		Read:   resourceTestRead,
 		Update: resourceTestUpdate,
 		Delete: resourceTestDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceTestImport,
+		},
 
 		Schema: map[string]*schema.Schema{
 			"bucket_id": {
@@ -36,9 +39,109 @@ func resourceRunscopeTest() *schema.Resource {
 				ForceNew: false,
 			},
 			"default_environment_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"delete_auto_created_environment": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"created_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"created_by": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"email": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"step_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"step_variables": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"trigger_url": {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"last_run": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"finished_at": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"assertion_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"assertion_success": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"script_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"script_success": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"variable_count": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"variable_success": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"region": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"environment_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"environment_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
 		},
 	}
 }
@@ -64,6 +167,24 @@ func resourceTestCreate(d *schema.ResourceData, meta interface{}) error {
 	d.SetId(createdTest.ID)
 	log.Printf("[INFO] test ID: %s", d.Id())
 
+	// Runscope always creates an environment for a new test and makes it the
+	// default, point the test at the configured environment instead.
+	if test.DefaultEnvironmentID != "" && test.DefaultEnvironmentID != createdTest.DefaultEnvironmentID {
+		test.ID = createdTest.ID
+		if _, err := client.UpdateTest(test); err != nil {
+			return fmt.Errorf("Failed to set default environment for test: %s", err)
+		}
+
+		if d.Get("delete_auto_created_environment").(bool) {
+			log.Printf("[INFO] Deleting auto created environment with id: %s from test: %s",
+				createdTest.DefaultEnvironmentID, d.Id())
+			err = client.DeleteEnvironment(&runscope.Environment{ID: createdTest.DefaultEnvironmentID}, test.Bucket)
+			if err != nil {
+				return fmt.Errorf("Failed to delete auto created environment: %s", err)
+			}
+		}
+	}
+
 	return resourceTestRead(d, meta)
 }
 
@@ -75,7 +196,7 @@ func resourceTestRead(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Error reading test: %s", err)
 	}
 
-	test, err := client.ReadTest(testFromResource)
+	test, triggerURL, err := readTest(client, testFromResource.Bucket.Key, testFromResource.ID)
 	if err != nil {
 		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "403") {
 			d.SetId("")
@@ -88,6 +209,13 @@ func resourceTestRead(d *schema.ResourceData, meta interface{}) error {
 	d.Set("name", test.Name)
 	d.Set("description", test.Description)
 	d.Set("default_environment_id", test.DefaultEnvironmentID)
+	d.Set("created_at", formatTime(test.CreatedAt))
+	d.Set("created_by", readCreatedBy(test.CreatedBy))
+	d.Set("step_ids", readStepIDs(test.Steps))
+	d.Set("step_variables", readStepVariables(test.Steps))
+	d.Set("last_run", readLastRun(test.LastRun))
+	d.Set("trigger_url", triggerURL)
+
 	return nil
 }
 
@@ -98,7 +226,9 @@ func resourceTestUpdate(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Error updating test: %s", err)
 	}
 
-	if d.HasChange("description") {
+	if d.HasChange("name") ||
+		d.HasChange("description") ||
+		d.HasChange("default_environment_id") {
 		client := meta.(*runscope.Client)
 		_, err = client.UpdateTest(testFromResource)
 
@@ -107,7 +237,31 @@ func resourceTestUpdate(d *schema.ResourceData, meta interface{}) error {
 		}
 	}
 
-	return nil
+	return resourceTestRead(d, meta)
+}
+
+func resourceTestImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.Split(d.Id(), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Unexpected format of ID (%s), expected bucket_key/test_id", d.Id())
+	}
+
+	d.Set("bucket_id", parts[0])
+	d.Set("delete_auto_created_environment", false)
+	d.SetId(parts[1])
+
+	err := resourceTestRead(d, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Id() == "" {
+		return nil, fmt.Errorf("Couldn't find test: %s", parts[1])
+	}
+
+	results := []*schema.ResourceData{d}
+
+	return results, nil
 }
 
 func resourceTestDelete(d *schema.ResourceData, meta interface{}) error {
@@ -142,5 +296,107 @@ func createTestFromResourceData(d *schema.ResourceData) (*runscope.Test, error)
 		test.Description = attr.(string)
 	}
 
+	if attr, ok := d.GetOk("default_environment_id"); ok {
+		test.DefaultEnvironmentID = attr.(string)
+	}
+
 	return test, nil
 }
+
+// readTest reads a test along with the url used to trigger it, which
+// runscope.Test doesn't include. See https://www.runscope.com/docs/api/tests#detail
+func readTest(client *runscope.Client, bucketID string, testID string) (*runscope.Test, string, error) {
+	data, err := apiRequest(client, "GET", fmt.Sprintf("/buckets/%s/tests/%s", bucketID, testID), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	test := runscope.NewTest()
+	if err := decodeAPIData(data, test); err != nil {
+		return nil, "", fmt.Errorf("Unable to read test %s: %s", testID, err)
+	}
+	test.Bucket = &runscope.Bucket{Key: bucketID}
+
+	triggerURL := ""
+	if raw, ok := data.(map[string]interface{}); ok {
+		triggerURL, _ = raw["trigger_url"].(string)
+	}
+
+	return test, triggerURL, nil
+}
+
+func readCreatedBy(contact *runscope.Contact) []interface{} {
+	if contact == nil {
+		return []interface{}{}
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"id":    contact.ID,
+			"name":  contact.Name,
+			"email": contact.Email,
+		},
+	}
+}
+
+// readStepVariables returns the names of the variables the steps of a test
+// define, including steps nested in conditions, in the order they run.
+func readStepVariables(steps []*runscope.TestStep) []string {
+	result := []string{}
+	for _, step := range steps {
+		for _, variable := range step.Variables {
+			if variable.Name != "" && !contains(result, variable.Name) {
+				result = append(result, variable.Name)
+			}
+		}
+
+		children, _ := step.Args["steps"].([]interface{})
+		for _, child := range children {
+			childStep, err := decodeTestStep(child)
+			if err != nil {
+				log.Printf("[WARN] Unable to read condition step %#v: %s", child, err)
+				continue
+			}
+
+			for _, name := range readStepVariables([]*runscope.TestStep{childStep.TestStep}) {
+				if !contains(result, name) {
+					result = append(result, name)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+func readStepIDs(steps []*runscope.TestStep) []string {
+	result := make([]string, 0, len(steps))
+	for _, step := range steps {
+		result = append(result, step.ID)
+	}
+
+	return result
+}
+
+func readLastRun(run *runscope.TestRun) []interface{} {
+	if run == nil {
+		return []interface{}{}
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"id":                run.ID,
+			"status":            run.Status,
+			"finished_at":       formatTime(run.FinishedAt),
+			"assertion_count":   run.AssertionCount,
+			"assertion_success": run.AssertionSuccess,
+			"script_count":      run.ScriptCount,
+			"script_success":    run.ScriptSuccess,
+			"variable_count":    run.ExtractorCount,
+			"variable_success":  run.ExtractorSuccess,
+			"region":            run.Region,
+			"environment_id":    run.EnvironmentUUID,
+			"environment_name":  run.EnvironmentName,
+		},
+	}
+}
@@ -0,0 +1,353 @@
+package runscope
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	runscope "github.com/ewilde/go-runscope"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceRunscopeTestSteps() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceTestStepsCreate,
+		Read:   resourceTestStepsRead,
+		Update: resourceTestStepsUpdate,
+		Delete: resourceTestStepsDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceTestStepsImport,
+		},
+		CustomizeDiff: resourceTestStepsCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"bucket_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"test_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"step": {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: runscopeStepSchema(),
+				},
+			},
+			"step_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func resourceTestStepsCreate(d *schema.ResourceData, meta interface{}) error {
+	testID := d.Get("test_id").(string)
+	log.Printf("[INFO] Creating steps for test: %s", testID)
+
+	client := meta.(*runscope.Client)
+	bucketID := d.Get("bucket_id").(string)
+	existing, err := readTestSteps(client, bucketID, testID)
+	if err != nil {
+		return fmt.Errorf("Couldn't find test: %s", err)
+	}
+
+	// Any steps the test already has are taken over, the resource manages
+	// every step of the test.
+	d.SetId(testID)
+	if err := applyTestSteps(d, meta, existing); err != nil {
+		return err
+	}
+
+	return resourceTestStepsRead(d, meta)
+}
+
+func resourceTestStepsRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*runscope.Client)
+
+	bucketID := d.Get("bucket_id").(string)
+	testSteps, err := readTestSteps(client, bucketID, d.Id())
+	if err != nil {
+		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "403") {
+			d.SetId("")
+			return nil
+		}
+
+		return fmt.Errorf("Couldn't find test: %s", err)
+	}
+
+	steps := make([]interface{}, 0, len(testSteps))
+	stepIDs := make([]string, 0, len(testSteps))
+	for _, step := range testSteps {
+		steps = append(steps, flattenStep(step))
+		stepIDs = append(stepIDs, step.ID)
+	}
+
+	d.Set("bucket_id", bucketID)
+	d.Set("test_id", d.Id())
+	if err := d.Set("step", steps); err != nil {
+		return fmt.Errorf("Error setting steps for test %s: %s", d.Id(), err)
+	}
+	d.Set("step_ids", stepIDs)
+	return nil
+}
+
+func resourceTestStepsUpdate(d *schema.ResourceData, meta interface{}) error {
+	d.Partial(false)
+
+	if oldSteps, newSteps := d.GetChange("step"); stepListChanged(oldSteps, newSteps) {
+		oldStepIDs, _ := d.GetChange("step_ids")
+		stepIDs := expandStringList(oldStepIDs.([]interface{}))
+
+		existing := make([]*testStep, 0, len(stepIDs))
+		for i, x := range oldSteps.([]interface{}) {
+			if i >= len(stepIDs) {
+				break
+			}
+
+			step := expandStep(x.(map[string]interface{}))
+			step.ID = stepIDs[i]
+			existing = append(existing, step)
+		}
+
+		if err := applyTestSteps(d, meta, existing); err != nil {
+			return err
+		}
+	}
+
+	return resourceTestStepsRead(d, meta)
+}
+
+func resourceTestStepsDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*runscope.Client)
+
+	bucketID := d.Get("bucket_id").(string)
+	for _, stepID := range expandStringList(d.Get("step_ids").([]interface{})) {
+		log.Printf("[INFO] Deleting step with id: %s from test: %s", stepID, d.Id())
+		err := client.DeleteTestStep(&runscope.TestStep{ID: stepID}, bucketID, d.Id())
+		if err != nil && !strings.Contains(err.Error(), "404") {
+			return fmt.Errorf("Error deleting step: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func resourceTestStepsImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.Split(d.Id(), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Unexpected format of ID (%s), expected bucket_key/test_id", d.Id())
+	}
+
+	d.Set("bucket_id", parts[0])
+	d.SetId(parts[1])
+
+	err := resourceTestStepsRead(d, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Id() == "" {
+		return nil, fmt.Errorf("Couldn't find test: %s", parts[1])
+	}
+
+	results := []*schema.ResourceData{d}
+
+	return results, nil
+}
+
+func resourceTestStepsCustomizeDiff(diff *schema.ResourceDiff, meta interface{}) error {
+	for i, x := range diff.Get("step").([]interface{}) {
+		if attributes, ok := x.(map[string]interface{}); ok {
+			if err := validateStep(attributes); err != nil {
+				return fmt.Errorf("step.%d: %s", i, err)
+			}
+		}
+	}
+
+	if oldSteps, newSteps := diff.GetChange("step"); stepListChanged(oldSteps, newSteps) {
+		return diff.SetNewComputed("step_ids")
+	}
+
+	return nil
+}
+
+// applyTestSteps makes the steps of a test match the configured list. Existing
+// steps that are unchanged are kept, changed steps are updated in place, extra
+// steps are created or deleted and finally the steps are put into the
+// configured order.
+func applyTestSteps(d *schema.ResourceData, meta interface{}, existing []*testStep) error {
+	client := meta.(*runscope.Client)
+	bucketID := d.Get("bucket_id").(string)
+	testID := d.Id()
+
+	newSteps := d.Get("step").([]interface{})
+	desired := make([]*testStep, 0, len(newSteps))
+	for _, x := range newSteps {
+		desired = append(desired, expandStep(x.(map[string]interface{})))
+	}
+
+	if err := validateStepIntegrations(client, bucketID, desired...); err != nil {
+		return err
+	}
+
+	used := make([]bool, len(existing))
+	deleted := make([]bool, len(existing))
+	stepIDs := make([]string, len(desired))
+
+	// Record every step known to exist, so a failure part way through
+	// doesn't lose track of steps that need deleting.
+	saveStepIDs := func() {
+		ids := []string{}
+		for _, stepID := range stepIDs {
+			if stepID != "" {
+				ids = append(ids, stepID)
+			}
+		}
+		for j, existingStep := range existing {
+			if !used[j] && !deleted[j] {
+				ids = append(ids, existingStep.ID)
+			}
+		}
+		d.Set("step_ids", ids)
+	}
+
+	// Keep steps that haven't changed, even if they've moved.
+	for i, step := range desired {
+		for j, existingStep := range existing {
+			if !used[j] && stepsEqual(step, existingStep) {
+				stepIDs[i] = existingStep.ID
+				used[j] = true
+				break
+			}
+		}
+	}
+
+	// Update the remaining existing steps in place, creating any extra steps.
+	next := 0
+	for i, step := range desired {
+		if stepIDs[i] != "" {
+			continue
+		}
+
+		for next < len(existing) && used[next] {
+			next++
+		}
+
+		if next < len(existing) {
+			step.ID = existing[next].ID
+			log.Printf("[DEBUG] step update: %#v", step)
+			if _, err := updateTestStep(client, step, bucketID, testID); err != nil {
+				saveStepIDs()
+				return fmt.Errorf("Error updating step: %s", err)
+			}
+			used[next] = true
+		} else {
+			log.Printf("[DEBUG] step create: %#v", step)
+			createdStep, err := createTestStep(client, step, bucketID, testID)
+			if err != nil {
+				saveStepIDs()
+				return fmt.Errorf("Failed to create step: %s", err)
+			}
+			step.ID = createdStep.ID
+		}
+
+		stepIDs[i] = step.ID
+	}
+
+	for j, existingStep := range existing {
+		if used[j] {
+			continue
+		}
+
+		log.Printf("[INFO] Deleting step with id: %s from test: %s", existingStep.ID, testID)
+		if err := client.DeleteTestStep(existingStep.TestStep, bucketID, testID); err != nil {
+			saveStepIDs()
+			return fmt.Errorf("Error deleting step: %s", err)
+		}
+		deleted[j] = true
+	}
+
+	saveStepIDs()
+
+	if len(stepIDs) > 1 {
+		log.Printf("[INFO] Ordering steps for test: %s", testID)
+		if err := reorderTestSteps(client, bucketID, testID, stepIDs); err != nil {
+			return fmt.Errorf("Error ordering steps: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// stepsEqual reports whether two steps would be sent to Runscope unchanged,
+// ignoring their ids, the formatting of JSON bodies and whitespace in scripts.
+func stepsEqual(a *testStep, b *testStep) bool {
+	x, y := *a.TestStep, *b.TestStep
+	x.ID, y.ID = "", ""
+	x.Body, y.Body = normalizeJSONBody(x.Body), normalizeJSONBody(y.Body)
+	x.Scripts, y.Scripts = normalizeScripts(x.Scripts), normalizeScripts(y.Scripts)
+	x.BeforeScripts, y.BeforeScripts = normalizeScripts(x.BeforeScripts), normalizeScripts(y.BeforeScripts)
+	return reflect.DeepEqual(x, y) && reflect.DeepEqual(a.Form, b.Form)
+}
+
+// stepListChanged reports whether a list of steps differs from the one in
+// state. HasChange can't be used as it never finds the nested sets of a step
+// equal.
+func stepListChanged(o interface{}, n interface{}) bool {
+	oldSteps, _ := o.([]interface{})
+	newSteps, _ := n.([]interface{})
+	if len(oldSteps) != len(newSteps) {
+		return true
+	}
+
+	for i := range newSteps {
+		oldStep, ok := oldSteps[i].(map[string]interface{})
+		if !ok {
+			return true
+		}
+
+		newStep, ok := newSteps[i].(map[string]interface{})
+		if !ok {
+			return true
+		}
+
+		if !stepsEqual(expandStep(oldStep), expandStep(newStep)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reorderTestSteps puts the steps of a test into the given order.
+func reorderTestSteps(client *runscope.Client, bucketID string, testID string, stepIDs []string) error {
+	steps := make([]map[string]string, 0, len(stepIDs))
+	for _, stepID := range stepIDs {
+		steps = append(steps, map[string]string{"id": stepID})
+	}
+
+	_, err := apiRequest(client, "PUT", fmt.Sprintf("/buckets/%s/tests/%s/steps", bucketID, testID), steps)
+	return err
+}
+
+func normalizeScripts(scripts []string) []string {
+	if scripts == nil {
+		return nil
+	}
+
+	normalized := make([]string, 0, len(scripts))
+	for _, script := range scripts {
+		normalized = append(normalized, normalizeScript(script))
+	}
+
+	return normalized
+}
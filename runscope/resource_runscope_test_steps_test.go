@@ -0,0 +1,267 @@
+package runscope
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	runscope "github.com/ewilde/go-runscope"
+	tfconfig "github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccTestSteps_basic(t *testing.T) {
+	teamID := os.Getenv("RUNSCOPE_TEAM_ID")
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckTestDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testRunscopeTestStepsConfigA, teamID),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckTestStepsOrder("runscope_test_steps.steps",
+						"http://step_a.com", "http://step_b.com", "http://step_c.com"),
+					resource.TestCheckResourceAttr("runscope_test_steps.steps", "step.#", "3"),
+					resource.TestCheckResourceAttr("runscope_test_steps.steps", "step_ids.#", "3"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testRunscopeTestStepsConfigReordered, teamID),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckTestStepsOrder("runscope_test_steps.steps",
+						"http://step_c.com", "http://step_a.com"),
+					resource.TestCheckResourceAttr("runscope_test_steps.steps", "step.#", "2"),
+				),
+			},
+			{
+				ResourceName:      "runscope_test_steps.steps",
+				ImportState:       true,
+				ImportStateIdFunc: testAccRunscopeTestImportStateIDFunc("runscope_test_steps.steps"),
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestResourceTestStepsUpdate_reorder(t *testing.T) {
+	steps := []map[string]interface{}{}
+	nextID := 0
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/tests/test-id":
+			return http.StatusOK, map[string]interface{}{"id": "test-id", "steps": steps}
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/tests/test-id/steps":
+			nextID++
+			req.Body["id"] = fmt.Sprintf("step-%d", nextID)
+			steps = append(steps, req.Body)
+			return http.StatusOK, steps
+		case req.Method == "PUT" && req.Path == "/buckets/bucket-key/tests/test-id/steps":
+			ordered := []map[string]interface{}{}
+			for _, item := range req.Items {
+				for _, step := range steps {
+					if step["id"] == item.(map[string]interface{})["id"] {
+						ordered = append(ordered, step)
+					}
+				}
+			}
+			steps = ordered
+			return http.StatusOK, steps
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	stepA := map[string]interface{}{"step_type": "request", "method": "GET", "url": "http://step_a.com"}
+	stepB := map[string]interface{}{"step_type": "request", "method": "GET", "url": "http://step_b.com"}
+
+	state := testResourceApply(t, resourceRunscopeTestSteps(), nil, map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"test_id":   "test-id",
+		"step":      []interface{}{stepA, stepB},
+	}, client)
+
+	if ids := []string{state.Attributes["step_ids.0"], state.Attributes["step_ids.1"]}; !reflect.DeepEqual(ids, []string{"step-1", "step-2"}) {
+		t.Fatalf("Expected step ids %v, actual %v", []string{"step-1", "step-2"}, ids)
+	}
+
+	*requests = nil
+	state = testResourceApply(t, resourceRunscopeTestSteps(), state, map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"test_id":   "test-id",
+		"step":      []interface{}{stepB, stepA},
+	}, client)
+
+	for _, req := range *requests {
+		if req.Method == "POST" || req.Method == "DELETE" || strings.Contains(req.Path, "/steps/") {
+			t.Fatalf("Expected steps to be reordered only, got %s %s", req.Method, req.Path)
+		}
+	}
+
+	if ids := []string{state.Attributes["step_ids.0"], state.Attributes["step_ids.1"]}; !reflect.DeepEqual(ids, []string{"step-2", "step-1"}) {
+		t.Fatalf("Expected step ids %v, actual %v", []string{"step-2", "step-1"}, ids)
+	}
+
+	if state.Attributes["step.0.url"] != "http://step_b.com" {
+		t.Fatalf("Expected first step url %s, actual %s", "http://step_b.com", state.Attributes["step.0.url"])
+	}
+}
+
+func TestResourceTestStepsDiff_unchanged(t *testing.T) {
+	steps := []map[string]interface{}{}
+	client, _, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		switch {
+		case req.Method == "GET" && req.Path == "/buckets/bucket-key/tests/test-id":
+			return http.StatusOK, map[string]interface{}{"id": "test-id", "steps": steps}
+		case req.Method == "POST" && req.Path == "/buckets/bucket-key/tests/test-id/steps":
+			req.Body["id"] = "step-1"
+			steps = append(steps, req.Body)
+			return http.StatusOK, steps
+		}
+
+		t.Errorf("Unexpected request %s %s", req.Method, req.Path)
+		return http.StatusNotFound, nil
+	})
+	defer server.Close()
+
+	raw := map[string]interface{}{
+		"bucket_id": "bucket-key",
+		"test_id":   "test-id",
+		"step": []interface{}{
+			map[string]interface{}{
+				"step_type": "request",
+				"method":    "GET",
+				"url":       "u",
+				"headers": []interface{}{
+					map[string]interface{}{"header": "Accept", "value": "application/json"},
+				},
+			},
+		},
+	}
+
+	state := testResourceApply(t, resourceRunscopeTestSteps(), nil, raw, client)
+
+	c, err := tfconfig.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	diff, err := resourceRunscopeTestSteps().Diff(state, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !diff.Empty() {
+		t.Fatalf("Expected no diff, got %#v", diff.Attributes)
+	}
+}
+
+func testAccCheckTestStepsOrder(n string, urls ...string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		client := testAccProvider.Meta().(*runscope.Client)
+
+		bucketID := rs.Primary.Attributes["bucket_id"]
+		test, err := client.ReadTest(&runscope.Test{ID: rs.Primary.ID, Bucket: &runscope.Bucket{Key: bucketID}})
+		if err != nil {
+			return err
+		}
+
+		if len(test.Steps) != len(urls) {
+			return fmt.Errorf("Expected %d steps, actual %d", len(urls), len(test.Steps))
+		}
+
+		for i, url := range urls {
+			if test.Steps[i].URL != url {
+				return fmt.Errorf("Steps not in correct order, want %s got %s at position %d", url, test.Steps[i].URL, i)
+			}
+		}
+
+		return nil
+	}
+}
+
+const testRunscopeTestStepsConfigA = `
+resource "runscope_test_steps" "steps" {
+  bucket_id = "${runscope_bucket.bucket.id}"
+  test_id   = "${runscope_test.test.id}"
+
+  step {
+    step_type = "request"
+    url       = "http://step_a.com"
+    method    = "GET"
+    variables {
+      name   = "httpStatus"
+      source = "response_status"
+    }
+  }
+
+  step {
+    step_type = "request"
+    url       = "http://step_b.com"
+    method    = "GET"
+  }
+
+  step {
+    step_type = "request"
+    url       = "http://step_c.com"
+    method    = "GET"
+  }
+}
+
+resource "runscope_test" "test" {
+  bucket_id   = "${runscope_bucket.bucket.id}"
+  name        = "runscope test"
+  description = "This is a test test..."
+}
+
+resource "runscope_bucket" "bucket" {
+  name      = "terraform-provider-test"
+  team_uuid = "%s"
+}
+`
+
+const testRunscopeTestStepsConfigReordered = `
+resource "runscope_test_steps" "steps" {
+  bucket_id = "${runscope_bucket.bucket.id}"
+  test_id   = "${runscope_test.test.id}"
+
+  step {
+    step_type = "request"
+    url       = "http://step_c.com"
+    method    = "GET"
+  }
+
+  step {
+    step_type = "request"
+    url       = "http://step_a.com"
+    method    = "GET"
+    variables {
+      name   = "httpStatus"
+      source = "response_status"
+    }
+  }
+}
+
+resource "runscope_test" "test" {
+  bucket_id   = "${runscope_bucket.bucket.id}"
+  name        = "runscope test"
+  description = "This is a test test..."
+}
+
+resource "runscope_bucket" "bucket" {
+  name      = "terraform-provider-test"
+  team_uuid = "%s"
+}
+`
@@ -2,6 +2,7 @@ package runscope
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"testing"
 
@@ -93,3 +94,196 @@ resource "runscope_bucket" "bucket" {
   team_uuid = "%s"
 }
 `
+
+func TestResourceTestUpdate_name(t *testing.T) {
+	name := "runscope test"
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		if req.Method == "PUT" {
+			name = req.Body["name"].(string)
+		}
+
+		return http.StatusOK, map[string]interface{}{
+			"id":                     "test-id",
+			"name":                   name,
+			"description":            "This is a test test...",
+			"default_environment_id": "environment-id",
+		}
+	})
+	defer server.Close()
+
+	state := &terraform.InstanceState{
+		ID: "test-id",
+		Attributes: map[string]string{
+			"id":                     "test-id",
+			"bucket_id":              "bucket-key",
+			"name":                   "runscope test",
+			"description":            "This is a test test...",
+			"default_environment_id": "environment-id",
+		},
+	}
+
+	newState := testResourceApply(t, resourceRunscopeTest(), state, map[string]interface{}{
+		"bucket_id":   "bucket-key",
+		"name":        "renamed test",
+		"description": "This is a test test...",
+	}, client)
+
+	var updates []*testAPIRequest
+	for _, req := range *requests {
+		if req.Method == "PUT" {
+			updates = append(updates, req)
+		}
+	}
+
+	if len(updates) != 1 {
+		t.Fatalf("Expected %d update requests, actual %d", 1, len(updates))
+	}
+
+	if updates[0].Path != "/buckets/bucket-key/tests/test-id" {
+		t.Fatalf("Expected update to %s, actual %s", "/buckets/bucket-key/tests/test-id", updates[0].Path)
+	}
+
+	if updates[0].Body["name"] != "renamed test" {
+		t.Fatalf("Expected name %s to be sent, actual %v", "renamed test", updates[0].Body["name"])
+	}
+
+	if newState.Attributes["name"] != "renamed test" {
+		t.Fatalf("Expected name %s in state, actual %s", "renamed test", newState.Attributes["name"])
+	}
+}
+
+func TestResourceTestCreate_defaultEnvironment(t *testing.T) {
+	defaultEnvironmentID := "auto-created-environment-id"
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		if req.Method == "PUT" {
+			defaultEnvironmentID = req.Body["default_environment_id"].(string)
+		}
+
+		return http.StatusOK, map[string]interface{}{
+			"id":                     "test-id",
+			"name":                   "runscope test",
+			"description":            "This is a test test...",
+			"default_environment_id": defaultEnvironmentID,
+		}
+	})
+	defer server.Close()
+
+	state := testResourceApply(t, resourceRunscopeTest(), nil, map[string]interface{}{
+		"bucket_id":                       "bucket-key",
+		"name":                            "runscope test",
+		"description":                     "This is a test test...",
+		"default_environment_id":          "shared-environment-id",
+		"delete_auto_created_environment": true,
+	}, client)
+
+	if state.Attributes["default_environment_id"] != "shared-environment-id" {
+		t.Fatalf("Expected default_environment_id %s, actual %s",
+			"shared-environment-id", state.Attributes["default_environment_id"])
+	}
+
+	deleted := false
+	for _, req := range *requests {
+		if req.Method == "DELETE" {
+			if req.Path != "/buckets/bucket-key/environments/auto-created-environment-id" {
+				t.Fatalf("Unexpected delete request: %s", req.Path)
+			}
+			deleted = true
+		}
+	}
+
+	if !deleted {
+		t.Fatalf("Expected auto created environment to be deleted")
+	}
+}
+
+func TestResourceTestRead_metadata(t *testing.T) {
+	client, requests, server := newTestAPIClient(t, func(req *testAPIRequest) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"id":                     "test-id",
+			"name":                   "runscope test",
+			"description":            "This is a test test...",
+			"default_environment_id": "environment-id",
+			"created_at":             1563212400,
+			"created_by": map[string]interface{}{
+				"id":    "person-id",
+				"name":  "bob",
+				"email": "bob@example.com",
+			},
+			"steps": []interface{}{
+				map[string]interface{}{
+					"id": "step-a",
+					"variables": []interface{}{
+						map[string]interface{}{"name": "token", "source": "response_json", "property": "data.token"},
+					},
+				},
+				map[string]interface{}{
+					"id":        "step-b",
+					"step_type": "condition",
+					"args": map[string]interface{}{
+						"steps": []interface{}{
+							map[string]interface{}{
+								"step_type": "request",
+								"variables": []interface{}{
+									map[string]interface{}{"name": "user_id", "source": "response_json", "property": "id"},
+									map[string]interface{}{"name": "token", "source": "response_headers", "property": "X-Token"},
+								},
+							},
+						},
+					},
+				},
+			},
+			"last_run": map[string]interface{}{
+				"id":                "run-id",
+				"status":            "pass",
+				"finished_at":       1563216000.5,
+				"assertion_count":   3,
+				"assertion_success": 2,
+				"extractor_count":   1,
+				"extractor_success": 1,
+				"region":            "us1",
+				"environment_name":  "test-environment",
+			},
+			"trigger_url": "https://api.runscope.com/radar/trigger-id/trigger",
+		}
+	})
+	defer server.Close()
+
+	d := resourceRunscopeTest().Data(&terraform.InstanceState{
+		ID:         "test-id",
+		Attributes: map[string]string{"bucket_id": "bucket-key"},
+	})
+
+	if err := resourceTestRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{
+		"created_at":                   "2019-07-15T17:40:00Z",
+		"created_by.0.name":            "bob",
+		"created_by.0.email":           "bob@example.com",
+		"step_ids.#":                   "2",
+		"step_ids.0":                   "step-a",
+		"step_ids.1":                   "step-b",
+		"step_variables.#":             "2",
+		"step_variables.0":             "token",
+		"step_variables.1":             "user_id",
+		"trigger_url":                  "https://api.runscope.com/radar/trigger-id/trigger",
+		"last_run.0.status":            "pass",
+		"last_run.0.finished_at":       "2019-07-15T18:40:00Z",
+		"last_run.0.assertion_success": "2",
+		"last_run.0.variable_success":  "1",
+		"last_run.0.region":            "us1",
+		"last_run.0.environment_name":  "test-environment",
+	}
+
+	if len(*requests) != 1 {
+		t.Errorf("Expected the test to be read with a single request, got %d", len(*requests))
+	}
+
+	attributes := d.State().Attributes
+	for key, value := range expected {
+		if attributes[key] != value {
+			t.Errorf("Expected %s to be %s, actual %s", key, value, attributes[key])
+		}
+	}
+}
@@ -1,5 +1,15 @@
 package runscope
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
 // Takes the result of flatmap.Expand for an array of strings
 // and returns a []*string
 func expandStringList(configured []interface{}) []string {
@@ -21,3 +31,106 @@ func contains(slice []string, search string) bool {
 	}
 	return false
 }
+
+// formatTime renders an optional api timestamp as RFC 3339, or an empty
+// string when it isn't set.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// normalizeScript returns a script without line ending differences and
+// trailing whitespace, which Runscope doesn't preserve.
+func normalizeScript(script string) string {
+	lines := strings.Split(strings.Replace(script, "\r\n", "\n", -1), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// suppressEquivalentScriptDiffs ignores changes to a script that only differ
+// in line endings or trailing whitespace.
+func suppressEquivalentScriptDiffs(k, old, new string, d *schema.ResourceData) bool {
+	return normalizeScript(old) == normalizeScript(new)
+}
+
+// normalizeJSONBody renders a JSON request body with sorted keys and
+// consistent indenting, so bodies that only differ in formatting compare
+// equal and changes show up line by line. Runscope {{variable}} tokens used
+// in place of a JSON value are kept as they are. Bodies that aren't JSON are
+// returned unchanged.
+func normalizeJSONBody(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return body
+	}
+
+	quoted, tokens := quoteTemplateTokens(trimmed)
+
+	decoder := json.NewDecoder(strings.NewReader(quoted))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return body
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return body
+	}
+
+	normalized := strings.TrimSuffix(buf.String(), "\n")
+	for i, token := range tokens {
+		normalized = strings.Replace(normalized, fmt.Sprintf("%q", templateTokenPlaceholder(i)), token, 1)
+	}
+
+	return normalized
+}
+
+// quoteTemplateTokens replaces {{variable}} tokens found outside of JSON
+// strings with quoted placeholders, returning the tokens that were replaced.
+func quoteTemplateTokens(body string) (string, []string) {
+	var result strings.Builder
+	tokens := []string{}
+	inString, escaped := false, false
+
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' && strings.HasPrefix(body[i:], "{{"):
+			if end := strings.Index(body[i:], "}}"); end != -1 {
+				tokens = append(tokens, body[i:i+end+2])
+				result.WriteString(fmt.Sprintf("%q", templateTokenPlaceholder(len(tokens)-1)))
+				i += end + 1
+				continue
+			}
+		}
+
+		result.WriteByte(c)
+	}
+
+	return result.String(), tokens
+}
+
+func templateTokenPlaceholder(i int) string {
+	return fmt.Sprintf("__runscope_template_token_%d__", i)
+}
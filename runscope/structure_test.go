@@ -30,3 +30,63 @@ func testConf() map[string]string {
 		"scripts.1": "log(\"hello 2\");",
 	}
 }
+
+func TestNormalizeJSONBody(t *testing.T) {
+	cases := []struct {
+		Body     string
+		Expected string
+	}{
+		{
+			Body:     `{"b": 1, "a": {"d": [1, 2], "c": "x"}}`,
+			Expected: "{\n  \"a\": {\n    \"c\": \"x\",\n    \"d\": [\n      1,\n      2\n    ]\n  },\n  \"b\": 1\n}",
+		},
+		{
+			Body:     "{\"id\": {{user_id}}, \"name\": \"{{name}} <{{email}}>\"}",
+			Expected: "{\n  \"id\": {{user_id}},\n  \"name\": \"{{name}} <{{email}}>\"\n}",
+		},
+		{
+			Body:     `{"price": 10.50}`,
+			Expected: "{\n  \"price\": 10.50\n}",
+		},
+		{
+			Body:     "a=1&b=2",
+			Expected: "a=1&b=2",
+		},
+		{
+			Body:     `{"broken": }`,
+			Expected: `{"broken": }`,
+		},
+		{
+			Body:     "",
+			Expected: "",
+		},
+	}
+
+	for i, tc := range cases {
+		actual := normalizeJSONBody(tc.Body)
+		if actual != tc.Expected {
+			t.Fatalf("%d: Got:\n\n%s\n\nExpected:\n\n%s\n", i, actual, tc.Expected)
+		}
+
+		if again := normalizeJSONBody(actual); again != actual {
+			t.Fatalf("%d: normalizing again changed the body to %s", i, again)
+		}
+	}
+}
+
+func TestNormalizeScript(t *testing.T) {
+	cases := []struct {
+		Script   string
+		Expected string
+	}{
+		{Script: "var a = 1;\r\nvar b = 2;  \r\n\r\n", Expected: "var a = 1;\nvar b = 2;"},
+		{Script: "  indented();\t\n", Expected: "  indented();"},
+		{Script: "", Expected: ""},
+	}
+
+	for i, tc := range cases {
+		if actual := normalizeScript(tc.Script); actual != tc.Expected {
+			t.Fatalf("%d: expected %q, actual %q", i, tc.Expected, actual)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package runscope
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	runscope "github.com/ewilde/go-runscope"
+)
+
+// testStep is a runscope test step with its form fields.
+type testStep struct {
+	*runscope.TestStep
+	Form map[string][]string `json:"form,omitempty"`
+}
+
+func newTestStep() *testStep {
+	return &testStep{TestStep: runscope.NewTestStep()}
+}
+
+// createTestStep adds a step to the end of a test. See https://www.runscope.com/docs/api/steps#add
+func createTestStep(client *runscope.Client, step *testStep, bucketID string, testID string) (*testStep, error) {
+	client.Lock()
+	defer client.Unlock()
+
+	data, err := apiRequest(client, "POST", fmt.Sprintf("/buckets/%s/tests/%s/steps", bucketID, testID), step)
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := decodeTestSteps(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("No steps returned creating step for test %s", testID)
+	}
+
+	return steps[len(steps)-1], nil
+}
+
+// readTestStep reads a single step of a test. See https://www.runscope.com/docs/api/steps#detail
+func readTestStep(client *runscope.Client, stepID string, bucketID string, testID string) (*testStep, error) {
+	data, err := apiRequest(client, "GET", fmt.Sprintf("/buckets/%s/tests/%s/steps/%s", bucketID, testID, stepID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeTestStep(data)
+}
+
+// readTestSteps reads the steps of a test, in the order they run.
+func readTestSteps(client *runscope.Client, bucketID string, testID string) ([]*testStep, error) {
+	data, err := apiRequest(client, "GET", fmt.Sprintf("/buckets/%s/tests/%s", bucketID, testID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	test, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Unexpected response reading test %s: %#v", testID, data)
+	}
+
+	return decodeTestSteps(test["steps"])
+}
+
+// clearedStepFields are the fields of a step sent with an empty value when
+// they aren't set, so fields removed from a step are removed remotely too.
+var clearedStepFields = map[string]interface{}{
+	"note":           "",
+	"body":           "",
+	"scripts":        []string{},
+	"before_scripts": []string{},
+	"auth":           map[string]string{},
+	"headers":        map[string][]string{},
+	"form":           map[string][]string{},
+	"assertions":     []interface{}{},
+	"variables":      []interface{}{},
+}
+
+// updateTestStep updates an existing step. See https://www.runscope.com/docs/api/steps#modify
+func updateTestStep(client *runscope.Client, step *testStep, bucketID string, testID string) (*testStep, error) {
+	payload, err := stepUpdatePayload(step)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := apiRequest(client, "PUT", fmt.Sprintf("/buckets/%s/tests/%s/steps/%s", bucketID, testID, step.ID), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeTestStep(data)
+}
+
+// stepUpdatePayload renders a step with every unset clearedStepFields field
+// given its empty value.
+func stepUpdatePayload(step *testStep) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(step)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	for field, empty := range clearedStepFields {
+		if _, ok := payload[field]; !ok {
+			payload[field] = empty
+		}
+	}
+
+	return payload, nil
+}
+
+// decodeTestStep converts the "data" of a step response to a testStep.
+func decodeTestStep(data interface{}) (*testStep, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	step := newTestStep()
+	if err := json.Unmarshal(encoded, step); err != nil {
+		return nil, fmt.Errorf("Unable to read step %s: %s", string(encoded), err)
+	}
+
+	if len(step.Form) == 0 {
+		step.Form = nil
+	}
+
+	return step, nil
+}
+
+// decodeTestSteps converts a list of steps from an api response.
+func decodeTestSteps(data interface{}) ([]*testStep, error) {
+	items, _ := data.([]interface{})
+	steps := make([]*testStep, 0, len(items))
+	for _, item := range items {
+		step, err := decodeTestStep(item)
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}